@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"image"
+	"time"
+
+	"github.com/mum4k/termdash"
+	"github.com/mum4k/termdash/terminal/tcell"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+type App struct {
+	ctx        context.Context
+	controller *termdash.Controller
+	redrawCh   chan bool
+	errorCh    chan error
+	term       *tcell.Terminal
+	size       image.Point
+	model      *model
+	widgets    *widgets
+	refresh    time.Duration
+}
+
+// New creates the TUI for repo, reloading the list every refresh
+func New(repo Repository, refresh time.Duration) (*App, error) {
+	redrawCh := make(chan bool)
+	errorCh := make(chan error)
+
+	m := newModel(repo)
+	m.refresh()
+
+	w, err := newWidgets()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.update(m); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	keys := func(k *terminalapi.Keyboard) {
+		if !m.handleKey(k.Key) {
+			cancel()
+			return
+		}
+
+		m.Lock()
+		err := w.update(m)
+		m.Unlock()
+
+		if err != nil {
+			errorCh <- err
+			return
+		}
+		redrawCh <- true
+	}
+
+	term, err := tcell.New()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	c, err := newGrid(w, term)
+	if err != nil {
+		cancel()
+		term.Close()
+		return nil, err
+	}
+
+	controller, err := termdash.NewController(term, c, termdash.KeyboardSubscriber(keys))
+	if err != nil {
+		cancel()
+		term.Close()
+		return nil, err
+	}
+
+	return &App{
+		ctx:        ctx,
+		controller: controller,
+		redrawCh:   redrawCh,
+		errorCh:    errorCh,
+		term:       term,
+		model:      m,
+		widgets:    w,
+		refresh:    refresh,
+	}, nil
+}
+
+func (a *App) resize() error {
+	if a.size.Eq(a.term.Size()) {
+		return nil
+	}
+
+	a.size = a.term.Size()
+	if err := a.term.Clear(); err != nil {
+		return err
+	}
+
+	return a.controller.Redraw()
+}
+
+// reload fetches the list again unless the user is typing a task
+func (a *App) reload() error {
+	a.model.Lock()
+	defer a.model.Unlock()
+
+	if a.model.mode != modeBrowse {
+		return nil
+	}
+
+	a.model.refresh()
+	if err := a.widgets.update(a.model); err != nil {
+		return err
+	}
+
+	return a.controller.Redraw()
+}
+
+func (a *App) Run() error {
+	// clean up when done
+	defer a.term.Close()
+	defer a.controller.Close()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	refresh := time.NewTicker(a.refresh)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-a.redrawCh:
+			if err := a.controller.Redraw(); err != nil {
+				return err
+			}
+		case err := <-a.errorCh:
+			if err != nil {
+				return err
+			}
+		case <-a.ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := a.resize(); err != nil {
+				return err
+			}
+		case <-refresh.C:
+			if err := a.reload(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/container/grid"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+func newGrid(w *widgets, t terminalapi.Terminal) (*container.Container, error) {
+	builder := grid.New()
+
+	builder.Add(
+		grid.RowHeightPerc(80,
+			grid.Widget(w.txtList,
+				container.Border(linestyle.Light),
+				container.BorderTitle("Todo items"),
+			),
+		),
+	)
+
+	builder.Add(
+		grid.RowHeightPerc(10,
+			grid.Widget(w.txtPrompt, container.Border(linestyle.Light)),
+		),
+	)
+
+	builder.Add(
+		grid.RowHeightPerc(10,
+			grid.Widget(w.txtStatus, container.Border(linestyle.Light)),
+		),
+	)
+
+	gridOpts, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := container.New(t, gridOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mum4k/termdash/keyboard"
+)
+
+type mode int
+
+const (
+	modeBrowse mode = iota
+	modeAdd
+	modeEdit
+)
+
+// model holds the TUI state, it's shared by the keyboard handler and the
+// refresh loop
+type model struct {
+	sync.Mutex
+	repo     Repository
+	items    []Item
+	selected int
+	mode     mode
+	input    []rune
+	status   string
+}
+
+func newModel(repo Repository) *model {
+	return &model{
+		repo: repo,
+	}
+}
+
+// refresh reloads the items from the repository, m must be locked
+func (m *model) refresh() {
+	items, err := m.repo.List()
+	if err != nil {
+		m.status = fmt.Sprintf("Error: %s", err)
+		return
+	}
+
+	m.items = items
+	if m.selected >= len(m.items) {
+		m.selected = len(m.items) - 1
+	}
+	if m.selected < 0 {
+		m.selected = 0
+	}
+}
+
+// run executes an API call and reloads the list, m must be locked
+func (m *model) run(msg string, f func() error) bool {
+	if err := f(); err != nil {
+		m.status = fmt.Sprintf("Error: %s", err)
+		return false
+	}
+
+	m.status = msg
+	m.refresh()
+	return true
+}
+
+// handleKey updates the state for a key press, it returns false if the
+// key should quit the app
+func (m *model) handleKey(k keyboard.Key) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.mode != modeBrowse {
+		m.handleInput(k)
+		return true
+	}
+
+	id := m.selected + 1
+	switch k {
+	case 'q', 'Q':
+		return false
+	case keyboard.KeyArrowUp, 'k':
+		if m.selected > 0 {
+			m.selected--
+		}
+	case keyboard.KeyArrowDown, 'j':
+		if m.selected < len(m.items)-1 {
+			m.selected++
+		}
+	case 'r':
+		m.status = "Refreshed"
+		m.refresh()
+	case 'a':
+		m.mode = modeAdd
+		m.input = nil
+	case 'e':
+		if len(m.items) > 0 {
+			m.mode = modeEdit
+			m.input = []rune(m.items[m.selected].Task)
+		}
+	case 'c':
+		if len(m.items) > 0 {
+			m.run(fmt.Sprintf("Item %d completed", id), func() error { return m.repo.Complete(id) })
+		}
+	case 'd':
+		if len(m.items) > 0 {
+			m.run(fmt.Sprintf("Item %d deleted", id), func() error { return m.repo.Delete(id) })
+		}
+	}
+
+	return true
+}
+
+// handleInput edits the input line while adding or editing a task
+func (m *model) handleInput(k keyboard.Key) {
+	switch k {
+	case keyboard.KeyEsc:
+		m.mode = modeBrowse
+		m.status = ""
+	case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+	case keyboard.KeyEnter:
+		task := string(m.input)
+		mode := m.mode
+		m.mode = modeBrowse
+		if task == "" {
+			return
+		}
+
+		if mode == modeAdd {
+			if m.run(fmt.Sprintf("Added task %q", task), func() error { return m.repo.Add(task) }) {
+				m.selected = len(m.items) - 1
+			}
+			return
+		}
+
+		id := m.selected + 1
+		m.run(fmt.Sprintf("Item %d updated", id), func() error { return m.repo.Update(id, task) })
+	default:
+		if k >= keyboard.KeySpace {
+			m.input = append(m.input, rune(k))
+		}
+	}
+}
+
+// prompt returns the input line for the current mode
+func (m *model) prompt() string {
+	switch m.mode {
+	case modeAdd:
+		return fmt.Sprintf("New task: %s_", string(m.input))
+	case modeEdit:
+		return fmt.Sprintf("Edit task %d: %s_", m.selected+1, string(m.input))
+	}
+
+	return "a: add  e: edit  c: complete  d: delete  r: refresh  j/k: move  q: quit"
+}
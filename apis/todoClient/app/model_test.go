@@ -0,0 +1,171 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mum4k/termdash/keyboard"
+)
+
+// memRepo is an in-memory Repository
+type memRepo struct {
+	items []Item
+}
+
+func (r *memRepo) List() ([]Item, error) {
+	return append([]Item{}, r.items...), nil
+}
+
+func (r *memRepo) Add(task string) error {
+	r.items = append(r.items, Item{Task: task})
+	return nil
+}
+
+func (r *memRepo) check(id int) error {
+	if id < 1 || id > len(r.items) {
+		return errors.New("not found")
+	}
+	return nil
+}
+
+func (r *memRepo) Complete(id int) error {
+	if err := r.check(id); err != nil {
+		return err
+	}
+	r.items[id-1].Done = true
+	return nil
+}
+
+func (r *memRepo) Delete(id int) error {
+	if err := r.check(id); err != nil {
+		return err
+	}
+	r.items = append(r.items[:id-1], r.items[id:]...)
+	return nil
+}
+
+func (r *memRepo) Update(id int, task string) error {
+	if err := r.check(id); err != nil {
+		return err
+	}
+	r.items[id-1].Task = task
+	return nil
+}
+
+func keys(s string) []keyboard.Key {
+	k := []keyboard.Key{}
+	for _, r := range s {
+		k = append(k, keyboard.Key(r))
+	}
+	return k
+}
+
+func TestHandleKey(t *testing.T) {
+	testCases := []struct {
+		name        string
+		keys        []keyboard.Key
+		expTasks    string
+		expSelected int
+		expStatus   string
+	}{
+		{
+			name:        "Navigate",
+			keys:        []keyboard.Key{keyboard.KeyArrowDown, 'j', 'j', 'k'},
+			expTasks:    "[{Task 1 false} {Task 2 false} {Task 3 false}]",
+			expSelected: 1,
+		},
+		{
+			name:        "Complete",
+			keys:        []keyboard.Key{'j', 'c'},
+			expTasks:    "[{Task 1 false} {Task 2 true} {Task 3 false}]",
+			expSelected: 1,
+			expStatus:   "Item 2 completed",
+		},
+		{
+			name:      "Delete",
+			keys:      []keyboard.Key{'d'},
+			expTasks:  "[{Task 2 false} {Task 3 false}]",
+			expStatus: "Item 1 deleted",
+		},
+		{
+			name:        "Add",
+			keys:        append(append([]keyboard.Key{'a'}, keys("Task 4x")...), keyboard.KeyBackspace2, keyboard.KeyEnter),
+			expTasks:    "[{Task 1 false} {Task 2 false} {Task 3 false} {Task 4 false}]",
+			expSelected: 3,
+			expStatus:   "Added task \"Task 4\"",
+		},
+		{
+			name:        "Edit",
+			keys:        append([]keyboard.Key{'j', 'j', 'e'}, keyboard.KeyBackspace, '!', keyboard.KeyEnter),
+			expTasks:    "[{Task 1 false} {Task 2 false} {Task ! false}]",
+			expSelected: 2,
+			expStatus:   "Item 3 updated",
+		},
+		{
+			name:     "CancelInput",
+			keys:     append([]keyboard.Key{'a', 'd', 'c'}, keyboard.KeyEsc),
+			expTasks: "[{Task 1 false} {Task 2 false} {Task 3 false}]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &memRepo{}
+			for i := 1; i <= 3; i++ {
+				repo.Add(fmt.Sprintf("Task %d", i))
+			}
+
+			m := newModel(repo)
+			m.refresh()
+
+			for _, k := range tc.keys {
+				if !m.handleKey(k) {
+					t.Fatalf("unexpected quit on key %v", k)
+				}
+			}
+
+			tasks := []struct {
+				Task string
+				Done bool
+			}{}
+			for _, i := range m.items {
+				tasks = append(tasks, struct {
+					Task string
+					Done bool
+				}{i.Task, i.Done})
+			}
+
+			if fmt.Sprint(tasks) != tc.expTasks {
+				t.Errorf("expected items %s, got %v", tc.expTasks, tasks)
+			}
+
+			if m.selected != tc.expSelected {
+				t.Errorf("expected item %d selected, got %d", tc.expSelected, m.selected)
+			}
+
+			if m.status != tc.expStatus {
+				t.Errorf("expected status %q, got %q", tc.expStatus, m.status)
+			}
+
+			if m.mode != modeBrowse {
+				t.Error("expected browse mode")
+			}
+		})
+	}
+}
+
+func TestHandleKeyQuit(t *testing.T) {
+	m := newModel(&memRepo{})
+
+	// q is a regular character while typing a task
+	m.handleKey('a')
+	if !m.handleKey('q') {
+		t.Fatal("unexpected quit while adding a task")
+	}
+
+	m.handleKey(keyboard.KeyEsc)
+	if m.handleKey('q') {
+		t.Error("expected q to quit")
+	}
+}
@@ -0,0 +1,22 @@
+package app
+
+import "time"
+
+// Item is a todo item as shown in the TUI
+type Item struct {
+	Task        string
+	Done        bool
+	CreatedAt   time.Time
+	CompletedAt time.Time
+	// Due is zero for items without a due date
+	Due time.Time
+}
+
+// Repository is the todo API used by the TUI, ids start at 1
+type Repository interface {
+	List() ([]Item, error)
+	Add(task string) error
+	Complete(id int) error
+	Delete(id int) error
+	Update(id int, task string) error
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+const timeFormat = "Jan/02 @15:04"
+
+type widgets struct {
+	txtList   *text.Text
+	txtPrompt *text.Text
+	txtStatus *text.Text
+}
+
+func newWidgets() (*widgets, error) {
+	w := &widgets{}
+	var err error
+
+	if w.txtList, err = text.New(); err != nil {
+		return nil, err
+	}
+
+	if w.txtPrompt, err = text.New(); err != nil {
+		return nil, err
+	}
+
+	if w.txtStatus, err = text.New(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// update writes the model state to the widgets, m must be locked
+func (w *widgets) update(m *model) error {
+	w.txtList.Reset()
+	if len(m.items) == 0 {
+		if err := w.txtList.Write("No items, press a to add one"); err != nil {
+			return err
+		}
+	}
+
+	for k, i := range m.items {
+		done := "-"
+		opts := []cell.Option{}
+		if i.Done {
+			done = "X"
+			opts = append(opts, cell.FgColor(cell.ColorGreen))
+		}
+		if k == m.selected {
+			opts = append(opts, cell.Inverse())
+		}
+
+		line := fmt.Sprintf("%s %3d  %-50s %s", done, k+1, i.Task, i.CreatedAt.Format(timeFormat))
+		if !i.Due.IsZero() {
+			line += "  due " + i.Due.Format(timeFormat)
+		}
+		line += "\n"
+		if err := w.txtList.Write(line, text.WriteCellOpts(opts...)); err != nil {
+			return err
+		}
+	}
+
+	if err := w.txtPrompt.Write(m.prompt(), text.WriteReplace()); err != nil {
+		return err
+	}
+
+	status := m.status
+	if status == "" {
+		status = " "
+	}
+	return w.txtStatus.Write(status, text.WriteReplace())
+}
@@ -4,11 +4,15 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestListAction(t *testing.T) {
@@ -53,7 +57,7 @@ func TestListAction(t *testing.T) {
 			}
 
 			var out bytes.Buffer
-			err := listAction(&out, url)
+			err := listAction(&out, url, testStore(t, url), outputFormat{})
 
 			if tc.expError != nil {
 				if err == nil {
@@ -119,7 +123,7 @@ func TestViewAction(t *testing.T) {
 
 			var out bytes.Buffer
 
-			err := viewAction(&out, url, tc.id)
+			err := viewAction(&out, url, testStore(t, url), outputFormat{}, tc.id)
 			if tc.expError != nil {
 				if err == nil {
 					t.Fatal("expected error, got no error")
@@ -177,7 +181,7 @@ func TestAddAction(t *testing.T) {
 	defer cleanup()
 
 	var out bytes.Buffer
-	if err := addAction(&out, url, args); err != nil {
+	if err := addAction(&out, url, testStore(t, url), args, time.Time{}); err != nil {
 		t.Fatalf("Unexpected error: %q", err)
 	}
 
@@ -213,7 +217,7 @@ func TestCompleteAction(t *testing.T) {
 
 	var out bytes.Buffer
 
-	if err := completeAction(&out, url, arg); err != nil {
+	if err := completeAction(&out, url, testStore(t, url), []string{arg}, false); err != nil {
 		t.Fatal("unexpected error")
 	}
 
@@ -244,7 +248,7 @@ func TestDelAction(t *testing.T) {
 
 	var out bytes.Buffer
 
-	if err := deleteAction(&out, url, arg); err != nil {
+	if err := deleteAction(&out, url, testStore(t, url), []string{arg}, false); err != nil {
 		t.Fatal("unexpected error")
 	}
 
@@ -252,3 +256,874 @@ func TestDelAction(t *testing.T) {
 		t.Errorf("unexpected output: %q\n expected: %q", out.String(), expOut)
 	}
 }
+
+func TestAddActionDue(t *testing.T) {
+	due := time.Date(2024, time.October, 18, 17, 0, 0, 0, time.UTC)
+	expBody := "{\"task\":\"Task 1\",\"due\":\"2024-10-18T17:00:00Z\"}\n"
+	expOut := "Added task \"Task 1\" to the list, due Oct/18 @17:00.\n"
+
+	url, cleanup := mockServer(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+
+		if string(body) != expBody {
+			t.Errorf("expected body %q, got %q", expBody, string(body))
+		}
+
+		w.WriteHeader(testResp["created"].Status)
+	})
+	defer cleanup()
+
+	var out bytes.Buffer
+	if err := addAction(&out, url, testStore(t, url), []string{"Task", "1"}, due); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	if expOut != out.String() {
+		t.Errorf("expected %q, got %q", expOut, out.String())
+	}
+}
+
+func TestBulkActions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		action   func(io.Writer, string) error
+		expReqs  []string
+		expOut   string
+		expError error
+	}{
+		{
+			name: "CompleteRange",
+			action: func(out io.Writer, url string) error {
+				return completeAction(out, url, testStore(t, url), []string{"1-2,2"}, false)
+			},
+			expReqs: []string{"PATCH /todo/1", "PATCH /todo/2"},
+			expOut:  "Item number 1 marked as completed\nItem number 2 marked as completed\n",
+		},
+		{
+			name: "CompleteAll",
+			action: func(out io.Writer, url string) error {
+				return completeAction(out, url, testStore(t, url), nil, true)
+			},
+			expReqs: []string{"GET /todo", "PATCH /todo/1"},
+			expOut:  "Item number 1 marked as completed\n",
+		},
+		{
+			name: "DeleteList",
+			action: func(out io.Writer, url string) error {
+				return deleteAction(out, url, testStore(t, url), []string{"1", "3"}, false)
+			},
+			expReqs: []string{"DELETE /todo/3", "DELETE /todo/1"},
+			expOut:  "Item number 3 deleted\nItem number 1 deleted\n",
+		},
+		{
+			name: "DeleteAllDone",
+			action: func(out io.Writer, url string) error {
+				return deleteAction(out, url, testStore(t, url), nil, true)
+			},
+			expReqs: []string{"GET /todo", "DELETE /todo/2"},
+			expOut:  "Item number 2 deleted\n",
+		},
+		{
+			name: "FilterWithIDs",
+			action: func(out io.Writer, url string) error {
+				return deleteAction(out, url, testStore(t, url), []string{"1"}, true)
+			},
+			expError: ErrInvalid,
+		},
+		{
+			name: "NoIDs",
+			action: func(out io.Writer, url string) error {
+				return completeAction(out, url, testStore(t, url), nil, false)
+			},
+			expError: ErrInvalid,
+		},
+		{
+			name: "InvalidRange",
+			action: func(out io.Writer, url string) error {
+				return deleteAction(out, url, testStore(t, url), []string{"5-2"}, false)
+			},
+			expError: ErrInvalid,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reqs := []string{}
+			url, cleanup := mockServer(func(w http.ResponseWriter, r *http.Request) {
+				reqs = append(reqs, r.Method+" "+r.URL.Path)
+				if r.Method == http.MethodGet {
+					w.WriteHeader(testResp["resultsDone"].Status)
+					fmt.Fprintln(w, testResp["resultsDone"].Body)
+					return
+				}
+				w.WriteHeader(testResp["noContent"].Status)
+			})
+			defer cleanup()
+
+			var out bytes.Buffer
+			err := tc.action(&out, url)
+			if tc.expError != nil {
+				if !errors.Is(err, tc.expError) {
+					t.Fatalf("expected error %q, got %q", tc.expError, err)
+				}
+				if len(reqs) != 0 {
+					t.Errorf("expected no requests, got %v", reqs)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+
+			if strings.Join(reqs, ",") != strings.Join(tc.expReqs, ",") {
+				t.Errorf("expected requests %v, got %v", tc.expReqs, reqs)
+			}
+
+			if tc.expOut != out.String() {
+				t.Errorf("expected %q, got %q", tc.expOut, out.String())
+			}
+		})
+	}
+}
+
+func TestBulkActionOffline(t *testing.T) {
+	url, cleanup := mockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(testResp["resultsMany"].Status)
+		fmt.Fprintln(w, testResp["resultsMany"].Body)
+	})
+	defer cleanup()
+
+	s := testStore(t, url)
+	stderr = io.Discard
+
+	var out bytes.Buffer
+	if err := listAction(&out, url, s, outputFormat{}); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	cleanup()
+
+	out.Reset()
+	if err := deleteAction(&out, url, s, []string{"1-2"}, false); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	expOut := "Server unreachable, queued del \"2\" for sync\n" +
+		"Server unreachable, queued del \"1\" for sync\n"
+	if expOut != out.String() {
+		t.Errorf("expected %q, got %q", expOut, out.String())
+	}
+
+	entries, err := s.journal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 || entries[0].Task != "Task 2" || entries[1].Task != "Task 1" {
+		t.Errorf("unexpected journal %+v", entries)
+	}
+}
+
+func TestOfflineActions(t *testing.T) {
+	url, cleanup := mockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(testResp["resultsMany"].Status)
+		fmt.Fprintln(w, testResp["resultsMany"].Body)
+	})
+	defer cleanup()
+
+	s := testStore(t, url)
+	var errOut bytes.Buffer
+	stderr = &errOut
+
+	var out bytes.Buffer
+	if err := listAction(&out, url, s, outputFormat{}); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	// server goes away, commands are served from the cache and queued
+	cleanup()
+
+	testCases := []struct {
+		name   string
+		action func(io.Writer) error
+		expOut string
+	}{
+		{
+			name:   "List",
+			action: func(out io.Writer) error { return listAction(out, url, s, outputFormat{}) },
+			expOut: "-  1  Task 1\n-  2  Task 2\n",
+		},
+		{
+			name:   "Complete",
+			action: func(out io.Writer) error { return completeAction(out, url, s, []string{"1"}, false) },
+			expOut: "Server unreachable, queued complete \"1\" for sync\n",
+		},
+		{
+			name:   "Add",
+			action: func(out io.Writer) error { return addAction(out, url, s, []string{"Task", "3"}, time.Time{}) },
+			expOut: "Server unreachable, queued add \"Task 3\" for sync\n",
+		},
+		{
+			name:   "ListQueued",
+			action: func(out io.Writer) error { return listAction(out, url, s, outputFormat{}) },
+			expOut: "X  1  Task 1\n-  2  Task 2\n-  3  Task 3\n",
+		},
+		{
+			name:   "View",
+			action: func(out io.Writer) error { return viewAction(out, url, s, outputFormat{}, "2") },
+			expOut: "Task:         Task 2\nCreated at:   Oct/28 @08:28\nCompleted:    No\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := tc.action(&out); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+
+			if tc.expOut != out.String() {
+				t.Errorf("expected %q, got %q", tc.expOut, out.String())
+			}
+		})
+	}
+
+	entries, err := s.journal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 queued entries, got %d", len(entries))
+	}
+
+	if entries[0].Task != "Task 1" {
+		t.Errorf("expected queued complete to record %q, got %q", "Task 1", entries[0].Task)
+	}
+
+	if !strings.Contains(errOut.String(), "showing cached list") {
+		t.Errorf("expected offline notice, got %q", errOut.String())
+	}
+}
+
+func TestSyncAction(t *testing.T) {
+	testCases := []struct {
+		name          string
+		journal       []journalEntry
+		skipConflicts bool
+		expError      error
+		expOut        string
+		expQueued     int
+	}{
+		{
+			name:   "Empty",
+			expOut: "Nothing to sync\n",
+		},
+		{
+			name: "Synced",
+			journal: []journalEntry{
+				{Op: opComplete, ID: 1, Task: "Task 1"},
+				{Op: opAdd, Task: "Task 3"},
+				{Op: opDelete, ID: 2, Task: "Task 2"},
+			},
+			expOut: "Synced complete \"1\"\nSynced add \"Task 3\"\nSynced del \"2\"\n",
+		},
+		{
+			name: "Conflict",
+			journal: []journalEntry{
+				{Op: opDelete, ID: 1, Task: "Other task"},
+				{Op: opAdd, Task: "Task 3"},
+			},
+			expError:  ErrConflict,
+			expQueued: 2,
+		},
+		{
+			name: "SkipConflicts",
+			journal: []journalEntry{
+				{Op: opDelete, ID: 5, Task: "Task 5"},
+				{Op: opAdd, Task: "Task 3"},
+			},
+			skipConflicts: true,
+			expOut:        "Skipped del \"5\": sync conflict: del 5: item no longer exists\nSynced add \"Task 3\"\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url, cleanup := mockServer(func(w http.ResponseWriter, r *http.Request) {
+				resp := testResp["resultsMany"]
+				switch r.Method {
+				case http.MethodPost:
+					resp = testResp["created"]
+				case http.MethodPatch, http.MethodDelete:
+					resp = testResp["noContent"]
+				}
+				w.WriteHeader(resp.Status)
+				fmt.Fprintln(w, resp.Body)
+			})
+			defer cleanup()
+
+			s := testStore(t, url)
+			if err := s.saveJournal(tc.journal); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			err := syncAction(&out, url, s, tc.skipConflicts)
+			if tc.expError != nil {
+				if !errors.Is(err, tc.expError) {
+					t.Fatalf("expected error %q, got %q", tc.expError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+
+			if tc.expOut != out.String() {
+				t.Errorf("expected %q, got %q", tc.expOut, out.String())
+			}
+
+			entries, err := s.journal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(entries) != tc.expQueued {
+				t.Errorf("expected %d queued entries, got %d", tc.expQueued, len(entries))
+			}
+		})
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	testCases := []struct {
+		name      string
+		output    string
+		noHeaders bool
+		resp      string
+		action    func(io.Writer, string, outputFormat) error
+		expOut    string
+		expError  error
+	}{
+		{
+			name:   "ListCSV",
+			output: "csv",
+			resp:   "resultsMany",
+			action: func(out io.Writer, url string, f outputFormat) error {
+				return listAction(out, url, testStore(t, url), f)
+			},
+			expOut: "id,task,done,created_at,completed_at,due_at\n" +
+				"1,Task 1,false,2019-10-28T08:28:38-04:00,,\n" +
+				"2,Task 2,false,2019-10-28T08:28:38-04:00,,\n",
+		},
+		{
+			name:      "ListCSVNoHeaders",
+			output:    "csv",
+			noHeaders: true,
+			resp:      "resultsMany",
+			action: func(out io.Writer, url string, f outputFormat) error {
+				return listAction(out, url, testStore(t, url), f)
+			},
+			expOut: "1,Task 1,false,2019-10-28T08:28:38-04:00,,\n" +
+				"2,Task 2,false,2019-10-28T08:28:38-04:00,,\n",
+		},
+		{
+			name:   "ListTemplate",
+			output: "go-template={{range .}}{{.ID}}={{.Task}};{{end}}",
+			resp:   "resultsMany",
+			action: func(out io.Writer, url string, f outputFormat) error {
+				return listAction(out, url, testStore(t, url), f)
+			},
+			expOut: "1=Task 1;2=Task 2;",
+		},
+		{
+			name:   "ViewJSON",
+			output: "json",
+			resp:   "resultsOne",
+			action: func(out io.Writer, url string, f outputFormat) error {
+				return viewAction(out, url, testStore(t, url), f, "1")
+			},
+			expOut: "{\n  \"id\": 1,\n  \"task\": \"Task 1\",\n  \"done\": false,\n" +
+				"  \"created_at\": \"2019-10-28T08:28:38.310097076-04:00\"\n}\n",
+		},
+		{
+			name:   "ViewYAML",
+			output: "yaml",
+			resp:   "resultsOne",
+			action: func(out io.Writer, url string, f outputFormat) error {
+				return viewAction(out, url, testStore(t, url), f, "1")
+			},
+			expOut: "id: 1\ntask: Task 1\ndone: false\ncreated_at: 2019-10-28T08:28:38.310097076-04:00\n",
+		},
+		{
+			name:     "Invalid",
+			output:   "xml",
+			expError: ErrInvalidFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := newOutputFormat(tc.output, tc.noHeaders)
+			if tc.expError != nil {
+				if !errors.Is(err, tc.expError) {
+					t.Fatalf("expected error %q, got %q", tc.expError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+
+			url, cleanup := mockServer(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(testResp[tc.resp].Status)
+				fmt.Fprintln(w, testResp[tc.resp].Body)
+			})
+			defer cleanup()
+
+			var out bytes.Buffer
+			if err := tc.action(&out, url, f); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+
+			if tc.expOut != out.String() {
+				t.Errorf("expected %q, got %q", tc.expOut, out.String())
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	testCases := []struct {
+		name        string
+		retries     int
+		timeout     time.Duration
+		failures    int
+		failStatus  int
+		retryAfter  string
+		delay       time.Duration
+		action      func(io.Writer, string) error
+		expError    error
+		expAttempts int
+	}{
+		{
+			name:        "RetryGet",
+			retries:     3,
+			failures:    2,
+			failStatus:  http.StatusServiceUnavailable,
+			action:      func(out io.Writer, url string) error { return listAction(out, url, testStore(t, url), outputFormat{}) },
+			expAttempts: 3,
+		},
+		{
+			name:        "RetryAfter",
+			retries:     1,
+			failures:    1,
+			failStatus:  http.StatusTooManyRequests,
+			retryAfter:  "0",
+			action:      func(out io.Writer, url string) error { return completeAction(out, url, testStore(t, url), []string{"1"}, false) },
+			expAttempts: 2,
+		},
+		{
+			name:        "GiveUp",
+			retries:     2,
+			failures:    5,
+			failStatus:  http.StatusBadGateway,
+			action:      func(out io.Writer, url string) error { return deleteAction(out, url, testStore(t, url), []string{"1"}, false) },
+			expError:    ErrInvalidResponse,
+			expAttempts: 3,
+		},
+		{
+			name:       "NoRetryPost",
+			retries:    3,
+			failures:   1,
+			failStatus: http.StatusServiceUnavailable,
+			action: func(out io.Writer, url string) error {
+				return addAction(out, url, testStore(t, url), []string{"Task 1"}, time.Time{})
+			},
+			expError:    ErrInvalidResponse,
+			expAttempts: 1,
+		},
+		{
+			name:       "NoRetryNotFound",
+			retries:    3,
+			failures:   1,
+			failStatus: http.StatusNotFound,
+			action: func(out io.Writer, url string) error {
+				return viewAction(out, url, testStore(t, url), outputFormat{}, "1")
+			},
+			expError:    ErrNotFound,
+			expAttempts: 1,
+		},
+		{
+			name:    "Timeout",
+			retries: 1,
+			timeout: 20 * time.Millisecond,
+			delay:   200 * time.Millisecond,
+			action: func(out io.Writer, url string) error {
+				return viewAction(out, url, testStore(t, url), outputFormat{}, "1")
+			},
+			expError:    ErrConnection,
+			expAttempts: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setConfig(t, "retries", tc.retries)
+			if tc.timeout > 0 {
+				setConfig(t, "timeout", tc.timeout)
+			}
+
+			attempts := 0
+			url, cleanup := mockServer(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if tc.delay > 0 {
+					time.Sleep(tc.delay)
+				}
+
+				if attempts <= tc.failures {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(tc.failStatus)
+					return
+				}
+
+				resp := testResp["resultsOne"]
+				switch r.Method {
+				case http.MethodPost:
+					resp = testResp["created"]
+				case http.MethodPatch, http.MethodDelete:
+					resp = testResp["noContent"]
+				}
+				w.WriteHeader(resp.Status)
+				fmt.Fprintln(w, resp.Body)
+			})
+			defer cleanup()
+
+			var out bytes.Buffer
+			err := tc.action(&out, url)
+			if tc.expError != nil {
+				if !errors.Is(err, tc.expError) {
+					t.Errorf("expected error %q, got %q", tc.expError, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %q", err)
+			}
+
+			if attempts != tc.expAttempts {
+				t.Errorf("expected %d attempts, got %d", tc.expAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestVerbose(t *testing.T) {
+	setConfig(t, "verbose", true)
+
+	var errOut bytes.Buffer
+	stderr = &errOut
+
+	url, cleanup := mockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(testResp["resultsMany"].Status)
+		fmt.Fprintln(w, testResp["resultsMany"].Body)
+	})
+	defer cleanup()
+
+	var out bytes.Buffer
+	if err := listAction(&out, url, testStore(t, url), outputFormat{}); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	expReq := fmt.Sprintf("> GET %s/todo\n", url)
+	if !strings.HasPrefix(errOut.String(), expReq) {
+		t.Errorf("expected request log %q, got %q", expReq, errOut.String())
+	}
+
+	if !strings.Contains(errOut.String(), "< 200 OK (") {
+		t.Errorf("expected response log, got %q", errOut.String())
+	}
+}
+
+func TestUpdateItem(t *testing.T) {
+	testCases := []struct {
+		name     string
+		resp     string
+		expError error
+	}{
+		{name: "Updated", resp: `{"data":{"update":{"id":1}}}`},
+		{name: "NotFound", resp: `{"data":{"update":null},"errors":[{"message":"not found: item 1"}]}`, expError: ErrNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url, cleanup := mockServer(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/graphql" {
+					t.Errorf("unexpected path %q", r.URL.Path)
+				}
+				if r.Method != http.MethodPost {
+					t.Error("expected POST method")
+				}
+
+				var req struct {
+					Variables map[string]any `json:"variables"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatal(err)
+				}
+				if req.Variables["id"] != float64(1) || req.Variables["task"] != "New task" {
+					t.Errorf("unexpected variables %v", req.Variables)
+				}
+
+				fmt.Fprintln(w, tc.resp)
+			})
+			defer cleanup()
+
+			err := apiRepo{apiRoot: url}.Update(1, "New task")
+			if tc.expError != nil {
+				if !errors.Is(err, tc.expError) {
+					t.Errorf("expected error %q, got %q", tc.expError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %q", err)
+			}
+		})
+	}
+}
+
+func TestAPIRepoList(t *testing.T) {
+	url, cleanup := mockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, `{"results": [{"Task": "Task 1", "Due": "2024-10-20T17:00:00Z"}], "date": 1, "total_results": 1}`)
+	})
+	defer cleanup()
+
+	items, err := apiRepo{apiRoot: url}.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	due := time.Date(2024, time.October, 20, 17, 0, 0, 0, time.UTC)
+	if len(items) != 1 || !items[0].Due.Equal(due) {
+		t.Errorf("expected one item due %s, got %+v", due, items)
+	}
+}
+
+func TestConfigActions(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "todoClient.yaml")
+
+	testCases := []struct {
+		name     string
+		action   func(io.Writer) error
+		expOut   string
+		expError error
+	}{
+		{
+			name: "SetDefault",
+			action: func(out io.Writer) error {
+				return configSetAction(out, cfgFile, "", "api-root", "http://localhost:8080")
+			},
+			expOut: "Set api-root in profile \"default\"\n",
+		},
+		{
+			name:   "SetProfile",
+			action: func(out io.Writer) error { return configSetAction(out, cfgFile, "team", "timeout", "30s") },
+			expOut: "Set timeout in profile \"team\"\n",
+		},
+		{
+			name:     "SetInvalidKey",
+			action:   func(out io.Writer) error { return configSetAction(out, cfgFile, "team", "colour", "blue") },
+			expError: ErrInvalidKey,
+		},
+		{
+			name:     "SetInvalidValue",
+			action:   func(out io.Writer) error { return configSetAction(out, cfgFile, "team", "timeout", "soon") },
+			expError: ErrInvalid,
+		},
+		{
+			name:   "UseProfile",
+			action: func(out io.Writer) error { return useProfileAction(out, cfgFile, "team") },
+			expOut: "Using profile \"team\"\n",
+		},
+		{
+			name:   "SetActiveProfile",
+			action: func(out io.Writer) error { return configSetAction(out, cfgFile, "", "output", "json") },
+			expOut: "Set output in profile \"team\"\n",
+		},
+		{
+			name:     "UseMissingProfile",
+			action:   func(out io.Writer) error { return useProfileAction(out, cfgFile, "staging") },
+			expError: ErrProfileNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := tc.action(&out)
+			if tc.expError != nil {
+				if !errors.Is(err, tc.expError) {
+					t.Fatalf("expected error %q, got %q", tc.expError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+
+			if tc.expOut != out.String() {
+				t.Errorf("expected %q, got %q", tc.expOut, out.String())
+			}
+		})
+	}
+
+	c, err := loadConfig(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expProfiles := "map[default:map[api-root:http://localhost:8080] team:map[output:json timeout:30s]]"
+	if fmt.Sprint(c.Profiles) != expProfiles {
+		t.Errorf("expected profiles %s, got %v", expProfiles, c.Profiles)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "todoClient.yaml")
+	c := configFile{
+		CurrentProfile: "staging",
+		Profiles: map[string]map[string]string{
+			"staging": {"api-root": "http://staging:8080"},
+			"team":    {"api-root": "http://team:8080"},
+		},
+	}
+	if err := c.save(cfgFile); err != nil {
+		t.Fatal(err)
+	}
+	setConfig(t, "config", cfgFile)
+
+	expAPIRoot := func(t *testing.T, exp string) {
+		t.Helper()
+		if err := initConfig(true); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+
+		var out bytes.Buffer
+		if err := configGetAction(&out, "api-root"); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+
+		if out.String() != exp+"\n" {
+			t.Errorf("expected api-root %q, got %q", exp, out.String())
+		}
+	}
+
+	t.Run("ActiveProfile", func(t *testing.T) {
+		expAPIRoot(t, "http://staging:8080")
+	})
+
+	t.Run("ProfileFlag", func(t *testing.T) {
+		setConfig(t, "profile", "team")
+		expAPIRoot(t, "http://team:8080")
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("TODO_API_ROOT", "http://env:8080")
+		expAPIRoot(t, "http://env:8080")
+	})
+
+	t.Run("Flag", func(t *testing.T) {
+		t.Setenv("TODO_API_ROOT", "http://env:8080")
+		f := rootCmd.PersistentFlags().Lookup("api-root")
+		if err := f.Value.Set("http://flag:8080"); err != nil {
+			t.Fatal(err)
+		}
+		f.Changed = true
+		defer func() {
+			f.Value.Set(f.DefValue)
+			f.Changed = false
+		}()
+
+		expAPIRoot(t, "http://flag:8080")
+	})
+
+	t.Run("MissingProfile", func(t *testing.T) {
+		setConfig(t, "profile", "production")
+		if err := initConfig(true); !errors.Is(err, ErrProfileNotFound) {
+			t.Errorf("expected error %q, got %q", ErrProfileNotFound, err)
+		}
+	})
+}
+
+// the config commands run with profiles that don't exist yet, so new
+// profiles can be set up
+func TestConfigCommands(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "todoClient.yaml")
+	setConfig(t, "config", cfgFile)
+	// use the --profile flag, not a value set by other tests
+	setConfig(t, "profile", nil)
+
+	f := rootCmd.PersistentFlags().Lookup("profile")
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		f.Value.Set(f.DefValue)
+		f.Changed = false
+	})
+
+	commands := [][]string{
+		{"--profile", "staging", "config", "set", "api-root", "http://staging:8080"},
+		{"--profile", "team", "config", "set", "api-root", "http://team:8080"},
+		{"config", "use-profile", "staging"},
+	}
+
+	for _, args := range commands {
+		f.Value.Set(f.DefValue)
+		f.Changed = false
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("%v: unexpected error: %q", args, err)
+		}
+	}
+
+	c, err := loadConfig(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expProfiles := "map[staging:map[api-root:http://staging:8080] team:map[api-root:http://team:8080]]"
+	if fmt.Sprint(c.Profiles) != expProfiles || c.CurrentProfile != "staging" {
+		t.Errorf("expected profiles %s using staging, got %v using %q", expProfiles, c.Profiles, c.CurrentProfile)
+	}
+
+	// other commands still need the profile to exist
+	f.Value.Set(f.DefValue)
+	rootCmd.SetArgs([]string{"--profile", "production", "list"})
+	if err := rootCmd.Execute(); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("expected error %q, got %q", ErrProfileNotFound, err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	setConfig(t, "token", "s3cr3t")
+
+	url, cleanup := mockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			t.Errorf("unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(testResp["resultsMany"].Status)
+		fmt.Fprintln(w, testResp["resultsMany"].Body)
+	})
+	defer cleanup()
+
+	var out bytes.Buffer
+	if err := listAction(&out, url, testStore(t, url), outputFormat{}); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+}
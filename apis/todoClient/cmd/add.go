@@ -4,10 +4,12 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -17,19 +19,40 @@ import (
 var addCmd = &cobra.Command{
 	Use:   "add <task>",
 	Short: "Add a new task to the list",
+	Long: `Add a new task to the list.
+
+Use --due to set a due date. It takes a date like 2024-10-20 or
+"2024-10-20 17:00", a relative time like "in 2 hours" or "in 3 days",
+or a day and a time like "tomorrow 5pm", "next friday", "fri noon" or
+"tonight". Dates without a time are due at 09:00.`,
+	Example: `  todoClient add Buy milk
+  todoClient add --due "tomorrow 5pm" Call the bank`,
 	SilenceUsage: true,
 	Args: cobra.MinimumNArgs(1),
 
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
+		s := newOfflineStore(viper.GetString("cache-dir"), apiRoot)
+
+		dueFlag, err := cmd.Flags().GetString("due")
+		if err != nil {
+			return err
+		}
 
-		return addAction(os.Stdout, apiRoot, args)
+		due, err := parseDue(dueFlag, time.Now())
+		if err != nil {
+			return err
+		}
+
+		return addAction(os.Stdout, apiRoot, s, args, due)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(addCmd)
 
+	addCmd.Flags().String("due", "", "Due date, e.g. \"tomorrow 5pm\" or \"next friday\"")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -41,16 +64,24 @@ func init() {
 	// addCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func addAction(out io.Writer, apiRoot string, args []string) error {
+func addAction(out io.Writer, apiRoot string, s *offlineStore, args []string, due time.Time) error {
 	task := strings.Join(args, " ")
-	if err := addItem(apiRoot, task); err != nil {
+	if err := addItem(apiRoot, task, due); err != nil {
+		if errors.Is(err, ErrConnection) {
+			return queueAction(out, s, journalEntry{Op: opAdd, Task: task, Due: due})
+		}
 		return err
 	}
 
-	return printAdd(out, task)
+	return printAdd(out, task, due)
 }
 
-func printAdd(out io.Writer, task string) error {
-	_, err := fmt.Fprintf(out, "Added task %q to the list.\n", task)
+func printAdd(out io.Writer, task string, due time.Time) error {
+	if due.IsZero() {
+		_, err := fmt.Fprintf(out, "Added task %q to the list.\n", task)
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "Added task %q to the list, due %s.\n", task, due.Format(timeFormat))
 	return err
 }
\ No newline at end of file
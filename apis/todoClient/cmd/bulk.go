@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// selectIDs returns the IDs given in args, or, with filter set, the IDs
+// of the items matching it. Filters read the cached list when the
+// server is unreachable
+func selectIDs(apiRoot string, s *offlineStore, args []string, filterName string, filter func(item) bool) ([]int, error) {
+	if filter == nil {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%w: give item IDs or --%s", ErrInvalid, filterName)
+		}
+		return parseIDs(args)
+	}
+
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%w: --%s does not take item IDs", ErrInvalid, filterName)
+	}
+
+	items, err := getAll(apiRoot)
+	switch {
+	case errors.Is(err, ErrConnection):
+		c, cacheErr := s.loadCache()
+		if cacheErr != nil {
+			return nil, err
+		}
+		printOffline(c)
+		items = c.Results
+	case errors.Is(err, ErrNotFound):
+		items = nil
+	case err != nil:
+		return nil, err
+	}
+
+	ids := []int{}
+	for k, i := range items {
+		if filter(i) {
+			ids = append(ids, k+1)
+		}
+	}
+
+	return ids, nil
+}
+
+// batchAction sends op for each ID in order, one request at a time as
+// the API has no batch endpoint. Once the server is unreachable, it
+// queues the remaining IDs for sync instead
+func batchAction(out io.Writer, s *offlineStore, op string, ids []int,
+	send func(int) error, print func(io.Writer, int) error) error {
+	if len(ids) == 0 {
+		_, err := fmt.Fprintln(out, "No matching items")
+		return err
+	}
+
+	offline := false
+	for _, id := range ids {
+		if !offline {
+			err := send(id)
+			if err == nil {
+				if err := print(out, id); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if !errors.Is(err, ErrConnection) {
+				return err
+			}
+			offline = true
+		}
+
+		if err := queueAction(out, s, journalEntry{Op: op, ID: id}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// descending sorts ids from the highest to the lowest, so deleting an
+// item doesn't shift the IDs still to be deleted
+func descending(ids []int) []int {
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	return ids
+}
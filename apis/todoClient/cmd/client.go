@@ -6,8 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/spf13/viper"
 )
 
 var (
@@ -23,6 +28,7 @@ type item struct {
 	Done        bool
 	CreatedAt   time.Time
 	CompletedAt time.Time
+	Due         time.Time
 }
 
 type response struct {
@@ -35,16 +41,120 @@ const timeFormat = "Jan/02 @15:04"
 
 func newClient() *http.Client {
 	c := &http.Client{
-		Timeout: 10 * time.Second,
+		Timeout: viper.GetDuration("timeout"),
 	}
 
 	return c
 }
 
+// idempotent requests are safe to send again after a transient failure
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodDelete, http.MethodPatch:
+		return true
+	}
+
+	return false
+}
+
+// retryable reports whether the request failed with a transient error
+func retryable(r *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch r.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// retryDelay honors the server Retry-After header, falling back to
+// exponential backoff with jitter
+func retryDelay(r *http.Response, attempt int) time.Duration {
+	if r != nil {
+		if ra := r.Header.Get("Retry-After"); ra != "" {
+			if secs, err := strconv.Atoi(ra); err == nil && secs >= 0 {
+				return time.Duration(secs) * time.Second
+			}
+			if t, err := http.ParseTime(ra); err == nil {
+				return max(time.Until(t), 0)
+			}
+		}
+	}
+
+	d := viper.GetDuration("retry-backoff") << attempt
+	if d <= 0 {
+		return 0
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// do sends req, retrying idempotent requests on transient failures
+func do(req *http.Request) (*http.Response, error) {
+	retries := 0
+	if idempotent(req.Method) {
+		retries = viper.GetInt("retries")
+	}
+
+	if token := viper.GetString("token"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	c := newClient()
+	for attempt := 0; ; attempt++ {
+		logRequest(req)
+		start := time.Now()
+		r, err := c.Do(req)
+		logResponse(r, err, time.Since(start))
+
+		if attempt >= retries || !retryable(r, err) {
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrConnection, err)
+			}
+			return r, nil
+		}
+
+		delay := retryDelay(r, attempt)
+		if r != nil {
+			r.Body.Close()
+		}
+		time.Sleep(delay)
+	}
+}
+
+func logRequest(req *http.Request) {
+	if viper.GetBool("verbose") {
+		fmt.Fprintf(stderr, "> %s %s\n", req.Method, req.URL)
+	}
+}
+
+func logResponse(r *http.Response, err error, d time.Duration) {
+	if !viper.GetBool("verbose") {
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "< error: %s (%s)\n", err, d.Round(time.Millisecond))
+		return
+	}
+
+	fmt.Fprintf(stderr, "< %s (%s)\n", r.Status, d.Round(time.Millisecond))
+}
+
 func getItems(url string) ([]item, error) {
-	r, err := newClient().Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrConnection, err)
+		return nil, err
+	}
+
+	r, err := do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer r.Body.Close()
 
@@ -105,7 +215,7 @@ func sendRequest(url, method, contentType string, expStatus int, body io.Reader)
 		req.Header.Set("Content-Type", contentType)
 	}
 
-	r, err := newClient().Do(req)
+	r, err := do(req)
 	if err != nil {
 		return err
 	}
@@ -126,14 +236,18 @@ func sendRequest(url, method, contentType string, expStatus int, body io.Reader)
 	return nil
 }
 
-func addItem(apiRoot, task string) error {
+func addItem(apiRoot, task string, due time.Time) error {
 	// compose endpoint url
 	u := fmt.Sprintf("%s/todo", apiRoot)
 	item := struct {
-		Task string `json:"task"`
+		Task string     `json:"task"`
+		Due  *time.Time `json:"due,omitempty"`
 	}{
 		Task: task,
 	}
+	if !due.IsZero() {
+		item.Due = &due
+	}
 	var body bytes.Buffer // req body
 
 	if err := json.NewEncoder(&body).Encode(item); err != nil {
@@ -153,4 +267,64 @@ func deleteItem(apiRoot string, id int) error {
 	u := fmt.Sprintf("%s/todo/%d", apiRoot, id)
 
 	return sendRequest(u, http.MethodDelete, "", http.StatusNoContent, nil)
-}
\ No newline at end of file
+}
+
+// updateItem changes the task of an item through the GraphQL endpoint,
+// the REST API has no way to edit an item
+func updateItem(apiRoot string, id int, task string) error {
+	u := fmt.Sprintf("%s/graphql", apiRoot)
+	query := struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}{
+		Query: `mutation ($id: Int!, $task: String!) { update(id: $id, task: $task) { id } }`,
+		Variables: map[string]any{
+			"id":   id,
+			"task": task,
+		},
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(query); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	r, err := do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		msg, err := io.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("cannot read body: %w", err)
+		}
+		return fmt.Errorf("%w: %s", ErrInvalidResponse, msg)
+	}
+
+	var resp struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		return err
+	}
+
+	if len(resp.Errors) > 0 {
+		err = ErrInvalidResponse
+		if strings.Contains(resp.Errors[0].Message, "not found") {
+			err = ErrNotFound
+		}
+		return fmt.Errorf("%w: %s", err, resp.Errors[0].Message)
+	}
+
+	return nil
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strconv"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,19 +14,32 @@ import (
 
 // completeCmd represents the complete command
 var completeCmd = &cobra.Command{
-	Use:   "complete <id>",
-	Short: "Mark a todo item as complete",
+	Use:   "complete <ids>",
+	Short: "Mark todo items as complete",
+	Long: `Mark todo items as complete.
+
+Items are given as IDs, lists and ranges, like "3", "1-5,8" or "1 3 5-7",
+or selected with --all to complete every pending item. Items are sent
+one request at a time, as the API has no batch endpoint.`,
 	SilenceUsage: true,
-	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
-		return completeAction(os.Stdout, apiRoot, args[0])
+		s := newOfflineStore(viper.GetString("cache-dir"), apiRoot)
+
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			return err
+		}
+
+		return completeAction(os.Stdout, apiRoot, s, args, all)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(completeCmd)
 
+	completeCmd.Flags().Bool("all", false, "Complete all pending items")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -39,18 +51,19 @@ func init() {
 	// completeCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-
-func completeAction(out io.Writer, apiRoot, arg string) error {
-	id, err := strconv.Atoi(arg)
-	if err != nil {
-		return fmt.Errorf("%w: id not a number", ErrNotNumber)
+func completeAction(out io.Writer, apiRoot string, s *offlineStore, args []string, all bool) error {
+	var pending func(item) bool
+	if all {
+		pending = func(i item) bool { return !i.Done }
 	}
 
-	if err := completeItem(apiRoot, id); err != nil {
+	ids, err := selectIDs(apiRoot, s, args, "all", pending)
+	if err != nil {
 		return err
 	}
 
-	return printComplete(out, id)
+	send := func(id int) error { return completeItem(apiRoot, id) }
+	return batchAction(out, s, opComplete, ids, send, printComplete)
 }
 
 func printComplete(out io.Writer, id int) error {
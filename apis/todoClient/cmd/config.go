@@ -0,0 +1,259 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrInvalidKey      = errors.New("invalid config key")
+)
+
+const defaultProfile = "default"
+
+// profileKeys are the settings a profile can hold, with a validator
+// for their values
+var profileKeys = map[string]func(string) error{
+	"api-root": func(string) error { return nil },
+	"token":    func(string) error { return nil },
+	"output": func(v string) error {
+		_, err := newOutputFormat(v, false)
+		return err
+	},
+	"timeout":       validateDuration,
+	"retry-backoff": validateDuration,
+	"retries": func(v string) error {
+		_, err := strconv.Atoi(v)
+		return err
+	},
+}
+
+// configFile is the layout of the todoClient config file
+type configFile struct {
+	CurrentProfile string                       `yaml:"current-profile,omitempty"`
+	Profiles       map[string]map[string]string `yaml:"profiles,omitempty"`
+}
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage config profiles",
+	Long: `Manage named profiles in the config file.
+
+A profile holds the api-root, token, output, timeout, retries and
+retry-backoff settings for one server. Settings are resolved in this
+order, the first one found wins:
+
+  1. command line flags
+  2. TODO_* environment variables, e.g. TODO_API_ROOT
+  3. the active profile, selected with --profile, TODO_PROFILE or
+     config use-profile
+  4. flag defaults
+
+Profiles don't have to exist for the config commands: config set
+--profile creates the profile it writes to.`,
+
+	// config set creates profiles, the profile asked for may not exist
+	// yet
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return initConfig(false)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:          "set <key> <value>",
+	Short:        "Set a value in a profile",
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configSetAction(os.Stdout, viper.GetString("config"), viper.GetString("profile"), args[0], args[1])
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:          "get <key>",
+	Short:        "Print the resolved value of a setting",
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configGetAction(os.Stdout, args[0])
+	},
+}
+
+var configUseProfileCmd = &cobra.Command{
+	Use:          "use-profile <name>",
+	Short:        "Make a profile the active one",
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return useProfileAction(os.Stdout, viper.GetString("config"), args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configUseProfileCmd)
+}
+
+func validateDuration(v string) error {
+	_, err := time.ParseDuration(v)
+	return err
+}
+
+func defaultConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".todoClient.yaml"
+	}
+
+	return filepath.Join(home, ".todoClient.yaml")
+}
+
+func loadConfig(cfgFile string) (configFile, error) {
+	c := configFile{}
+	f, err := os.ReadFile(cfgFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c, nil
+		}
+		return c, err
+	}
+
+	if err := yaml.Unmarshal(f, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", cfgFile, err)
+	}
+
+	return c, nil
+}
+
+func (c configFile) save(cfgFile string) error {
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cfgFile, out, 0600)
+}
+
+// profileName returns the requested profile, or the active one
+func (c configFile) profileName(requested string) string {
+	switch {
+	case requested != "":
+		return requested
+	case c.CurrentProfile != "":
+		return c.CurrentProfile
+	}
+
+	return defaultProfile
+}
+
+// initConfig loads the active profile from the config file into viper,
+// below flags and environment variables in precedence. With mustExist,
+// a profile asked for with --profile or TODO_PROFILE has to be in the
+// config file
+func initConfig(mustExist bool) error {
+	viper.AutomaticEnv()
+
+	c, err := loadConfig(viper.GetString("config"))
+	if err != nil {
+		return err
+	}
+
+	requested := viper.GetString("profile")
+	name := c.profileName(requested)
+	p, ok := c.Profiles[name]
+	if !ok {
+		if requested != "" && mustExist {
+			return fmt.Errorf("%w: %q", ErrProfileNotFound, requested)
+		}
+		return nil
+	}
+
+	settings := make(map[string]any, len(p))
+	for k, v := range p {
+		settings[k] = v
+	}
+
+	return viper.MergeConfigMap(settings)
+}
+
+func configSetAction(out io.Writer, cfgFile, profile, key, value string) error {
+	validate, ok := profileKeys[key]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	if err := validate(value); err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalid, key, err)
+	}
+
+	c, err := loadConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	name := c.profileName(profile)
+	if c.Profiles == nil {
+		c.Profiles = map[string]map[string]string{}
+	}
+	if c.Profiles[name] == nil {
+		c.Profiles[name] = map[string]string{}
+	}
+	c.Profiles[name][key] = value
+
+	if err := c.save(cfgFile); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "Set %s in profile %q\n", key, name)
+	return err
+}
+
+func configGetAction(out io.Writer, key string) error {
+	if _, ok := profileKeys[key]; !ok && viper.Get(key) == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	_, err := fmt.Fprintln(out, viper.GetString(key))
+	return err
+}
+
+func useProfileAction(out io.Writer, cfgFile, name string) error {
+	c, err := loadConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := c.Profiles[name]; !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%w: %q, available profiles: %v", ErrProfileNotFound, name, names)
+	}
+
+	c.CurrentProfile = name
+	if err := c.save(cfgFile); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "Using profile %q\n", name)
+	return err
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strconv"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,18 +14,34 @@ import (
 
 // delCmd represents the del command
 var delCmd = &cobra.Command{
-	Use:   "del <id>",
-	Short: "Deletes an item",
+	Use:   "del <ids>",
+	Short: "Deletes items",
+	Long: `Deletes items.
+
+Items are given as IDs, lists and ranges, like "3", "1-5,8" or "1 3 5-7",
+or selected with --all-done to delete every completed item. Items are
+deleted from the highest ID down, so the IDs refer to the list as it
+was before the command ran.`,
+	SilenceUsage: true,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
-		return deleteAction(os.Stdout, apiRoot, args[0])
+		s := newOfflineStore(viper.GetString("cache-dir"), apiRoot)
+
+		allDone, err := cmd.Flags().GetBool("all-done")
+		if err != nil {
+			return err
+		}
+
+		return deleteAction(os.Stdout, apiRoot, s, args, allDone)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(delCmd)
 
+	delCmd.Flags().Bool("all-done", false, "Delete all completed items")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -38,17 +53,19 @@ func init() {
 	// delCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func deleteAction(out io.Writer, apiRoot, arg string) error {
-	id, err := strconv.Atoi(arg)
-	if err != nil {
-		return fmt.Errorf("%w: id not a number", ErrNotNumber)
+func deleteAction(out io.Writer, apiRoot string, s *offlineStore, args []string, allDone bool) error {
+	var done func(item) bool
+	if allDone {
+		done = func(i item) bool { return i.Done }
 	}
 
-	if err := deleteItem(apiRoot, id); err != nil {
+	ids, err := selectIDs(apiRoot, s, args, "all-done", done)
+	if err != nil {
 		return err
 	}
 
-	return printDelete(out, id)
+	send := func(id int) error { return deleteItem(apiRoot, id) }
+	return batchAction(out, s, opDelete, descending(ids), send, printDelete)
 }
 
 func printDelete(out io.Writer, id int) error {
@@ -36,6 +36,7 @@ func TestIntegration(t *testing.T) {
 	today := time.Now().Format("Jan/02")
 	task := randomTaskName(t)
 	taskId := ""
+	s := testStore(t, apiRoot)
 
 	t.Run("AddTask", func(t *testing.T) {
 		args := []string{task}
@@ -43,7 +44,7 @@ func TestIntegration(t *testing.T) {
 
 		var out bytes.Buffer
 
-		if err := addAction(&out, apiRoot, args); err != nil {
+		if err := addAction(&out, apiRoot, s, args, time.Time{}); err != nil {
 			t.Fatalf("unexpected error: %q", err)
 		}
 
@@ -54,7 +55,7 @@ func TestIntegration(t *testing.T) {
 
 	t.Run("ListTasks", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := listAction(&out, apiRoot); err != nil {
+		if err := listAction(&out, apiRoot, s, outputFormat{}); err != nil {
 			t.Fatalf("Unexpected error: %q", err)
 		}
 
@@ -82,7 +83,7 @@ func TestIntegration(t *testing.T) {
 
 	vRes := t.Run("ViewTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := viewAction(&out, apiRoot, taskId); err != nil {
+		if err := viewAction(&out, apiRoot, s, outputFormat{}, taskId); err != nil {
 			t.Fatalf("Unexpected error: %q", err)
 		}
 
@@ -106,7 +107,7 @@ func TestIntegration(t *testing.T) {
 
 	t.Run("CompleteTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := completeAction(&out, apiRoot, taskId); err != nil {
+		if err := completeAction(&out, apiRoot, s, []string{taskId}, false); err != nil {
 			t.Fatalf("Unexpected error: %q", err)
 		}
 
@@ -118,7 +119,7 @@ func TestIntegration(t *testing.T) {
 
 	t.Run("ListCompletedTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := listAction(&out, apiRoot); err != nil {
+		if err := listAction(&out, apiRoot, s, outputFormat{}); err != nil {
 			t.Fatalf("Unexpected error: %q", err)
 		}
 
@@ -144,7 +145,7 @@ func TestIntegration(t *testing.T) {
 
 	t.Run("DeleteTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := deleteAction(&out, apiRoot, taskId); err != nil {
+		if err := deleteAction(&out, apiRoot, s, []string{taskId}, false); err != nil {
 			t.Fatalf("Unexpected error: %q", err)
 		}
 
@@ -156,7 +157,7 @@ func TestIntegration(t *testing.T) {
 
 	t.Run("ListDeletedTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := listAction(&out, apiRoot); err != nil {
+		if err := listAction(&out, apiRoot, s, outputFormat{}); err != nil {
 			t.Fatalf("Unexpected error: %q", err)
 		}
 
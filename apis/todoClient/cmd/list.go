@@ -4,6 +4,7 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -19,8 +20,14 @@ var listCmd = &cobra.Command{
 	Short:        "List todo items",
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := outputFromConfig()
+		if err != nil {
+			return err
+		}
+
 		apiRoot := viper.GetString("api-root")
-		return listAction(os.Stdout, apiRoot)
+		s := newOfflineStore(viper.GetString("cache-dir"), apiRoot)
+		return listAction(os.Stdout, apiRoot, s, f)
 	},
 }
 
@@ -38,16 +45,38 @@ func init() {
 	// listCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func listAction(out io.Writer, apiRoot string) error {
+func listAction(out io.Writer, apiRoot string, s *offlineStore, f outputFormat) error {
 	items, err := getAll(apiRoot)
+	if errors.Is(err, ErrConnection) {
+		c, cacheErr := s.loadCache()
+		if cacheErr != nil {
+			return err
+		}
+
+		printOffline(c)
+		return printAll(out, c.Results, f)
+	}
+
 	if err != nil {
 		return err
 	}
 
-	return printAll(out, items)
+	if err := s.saveCache(items); err != nil {
+		return err
+	}
+
+	return printAll(out, items, f)
 }
 
-func printAll(out io.Writer, items []item) error {
+func printAll(out io.Writer, items []item, f outputFormat) error {
+	if !f.isText() {
+		views := make([]itemView, 0, len(items))
+		for k, v := range items {
+			views = append(views, newItemView(k+1, v))
+		}
+		return f.write(out, views, views)
+	}
+
 	w := tabwriter.NewWriter(out, 3, 2, 0, ' ', 0)
 	for k, v := range items {
 		done := "-"
@@ -3,6 +3,11 @@ package cmd
 import (
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
 )
 
 var testResp = map[string]struct {
@@ -43,6 +48,27 @@ var testResp = map[string]struct {
 ],
 "date": 1572265440,
 "total_results": 1
+}`,
+	},
+	"resultsDone": {
+		Status: http.StatusOK,
+		Body: `{
+"results": [
+	{
+		"Task": "Task 1",
+		"Done": false,
+		"CreatedAt": "2019-10-28T08:28:38.310097076-04:00",
+		"CompletedAt": "0001-01-01T00:00:00Z"
+	},
+	{
+		"Task": "Task 2",
+		"Done": true,
+		"CreatedAt": "2019-10-28T08:28:38.323447798-04:00",
+		"CompletedAt": "2019-10-29T08:28:38.323447798-04:00"
+	}
+],
+"date": 1572265440,
+"total_results": 2
 }`,
 	},
 	"noResults": {
@@ -74,4 +100,24 @@ var testResp = map[string]struct {
 func mockServer(h http.HandlerFunc) (string, func()) {
 	ts := httptest.NewServer(h)
 	return ts.URL, func() {ts.Close()}
-}
\ No newline at end of file
+}
+
+// testStore returns an offline store in a temporary directory
+func testStore(t *testing.T, apiRoot string) *offlineStore {
+	t.Helper()
+	return newOfflineStore(t.TempDir(), apiRoot)
+}
+
+// setConfig overrides a config value for the duration of the test
+func setConfig(t *testing.T, key string, value any) {
+	t.Helper()
+	old := viper.Get(key)
+	viper.Set(key, value)
+	t.Cleanup(func() { viper.Set(key, old) })
+}
+
+func TestMain(m *testing.M) {
+	// keep retries on unreachable servers fast
+	viper.Set("retry-backoff", time.Millisecond)
+	os.Exit(m.Run())
+}
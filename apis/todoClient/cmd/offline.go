@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	ErrNotCached = errors.New("no cached list")
+	ErrConflict  = errors.New("sync conflict")
+)
+
+// journal operations
+const (
+	opAdd      = "add"
+	opComplete = "complete"
+	opDelete   = "del"
+)
+
+// offline messages are written here so they don't mix with the
+// command output
+var stderr io.Writer = os.Stderr
+
+// journalEntry is a command queued while the server was unreachable.
+// For complete and del, Task holds the task the ID referred to when it
+// was queued, so sync can detect that the list changed in the meantime
+type journalEntry struct {
+	Op       string    `json:"op"`
+	ID       int       `json:"id,omitempty"`
+	Task     string    `json:"task"`
+	Due      time.Time `json:"due"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+type cache struct {
+	Date    time.Time `json:"date"`
+	Results []item    `json:"results"`
+}
+
+// offlineStore keeps the last list and the queued commands for one API
+// root in dir
+type offlineStore struct {
+	cacheFile   string
+	journalFile string
+}
+
+func newOfflineStore(dir, apiRoot string) *offlineStore {
+	sum := sha256.Sum256([]byte(apiRoot))
+	key := hex.EncodeToString(sum[:4])
+
+	return &offlineStore{
+		cacheFile:   filepath.Join(dir, fmt.Sprintf("cache-%s.json", key)),
+		journalFile: filepath.Join(dir, fmt.Sprintf("journal-%s.json", key)),
+	}
+}
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "todoClient")
+}
+
+func readJSON(file string, v any) (bool, error) {
+	f, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if len(f) == 0 {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(f, v)
+}
+
+func writeJSON(file string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	js, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, js, 0644)
+}
+
+func (s *offlineStore) loadCache() (cache, error) {
+	var c cache
+	found, err := readJSON(s.cacheFile, &c)
+	if err != nil {
+		return c, err
+	}
+
+	if !found {
+		return c, ErrNotCached
+	}
+
+	return c, nil
+}
+
+func (s *offlineStore) saveCache(items []item) error {
+	return writeJSON(s.cacheFile, cache{
+		Date:    time.Now(),
+		Results: items,
+	})
+}
+
+func (s *offlineStore) journal() ([]journalEntry, error) {
+	entries := []journalEntry{}
+	if _, err := readJSON(s.journalFile, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s *offlineStore) saveJournal(entries []journalEntry) error {
+	if len(entries) == 0 {
+		if err := os.Remove(s.journalFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	return writeJSON(s.journalFile, entries)
+}
+
+// queue appends e to the journal and applies it to the cached list so
+// offline list and view reflect it
+func (s *offlineStore) queue(e journalEntry) error {
+	c, err := s.loadCache()
+	cached := err == nil
+	if err != nil && !errors.Is(err, ErrNotCached) {
+		return err
+	}
+
+	if e.Op != opAdd && e.ID >= 1 && e.ID <= len(c.Results) {
+		e.Task = c.Results[e.ID-1].Task
+	}
+	e.QueuedAt = time.Now()
+
+	entries, err := s.journal()
+	if err != nil {
+		return err
+	}
+
+	if err := s.saveJournal(append(entries, e)); err != nil {
+		return err
+	}
+
+	if !cached {
+		return nil
+	}
+
+	c.Results = apply(c.Results, e)
+	return writeJSON(s.cacheFile, c)
+}
+
+// apply returns items with e applied to them
+func apply(items []item, e journalEntry) []item {
+	switch e.Op {
+	case opAdd:
+		return append(items, item{Task: e.Task, CreatedAt: e.QueuedAt, Due: e.Due})
+	case opComplete:
+		if e.ID >= 1 && e.ID <= len(items) {
+			items[e.ID-1].Done = true
+			items[e.ID-1].CompletedAt = e.QueuedAt
+		}
+	case opDelete:
+		if e.ID >= 1 && e.ID <= len(items) {
+			items = append(items[:e.ID-1], items[e.ID:]...)
+		}
+	}
+
+	return items
+}
+
+func printOffline(c cache) {
+	fmt.Fprintf(stderr, "Server unreachable, showing cached list from %s\n", c.Date.Format(timeFormat))
+}
+
+func printQueued(out io.Writer, e journalEntry) error {
+	_, err := fmt.Fprintf(out, "Server unreachable, queued %s %q for sync\n", e.Op, e.queuedArg())
+	return err
+}
+
+func (e journalEntry) queuedArg() string {
+	if e.Op == opAdd {
+		return e.Task
+	}
+
+	return fmt.Sprint(e.ID)
+}
+
+func queueAction(out io.Writer, s *offlineStore, e journalEntry) error {
+	if err := s.queue(e); err != nil {
+		return err
+	}
+
+	return printQueued(out, e)
+}
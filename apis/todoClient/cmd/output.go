@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidFormat = errors.New("invalid output format")
+
+// supported values for the --output flag
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatYAML     = "yaml"
+	formatCSV      = "csv"
+	formatTemplate = "go-template"
+)
+
+// outputFormat controls how items are printed, the zero value prints
+// text
+type outputFormat struct {
+	format    string
+	tmpl      *template.Template
+	noHeaders bool
+}
+
+// itemView is the representation of an item in structured output
+type itemView struct {
+	ID          int        `json:"id" yaml:"id"`
+	Task        string     `json:"task" yaml:"task"`
+	Done        bool       `json:"done" yaml:"done"`
+	CreatedAt   time.Time  `json:"created_at" yaml:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" yaml:"completed_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty" yaml:"due_at,omitempty"`
+}
+
+func newOutputFormat(output string, noHeaders bool) (outputFormat, error) {
+	f := outputFormat{
+		format:    output,
+		noHeaders: noHeaders,
+	}
+
+	switch {
+	case output == "" || output == formatText:
+		f.format = formatText
+	case output == formatJSON, output == formatYAML, output == formatCSV:
+	case strings.HasPrefix(output, formatTemplate+"="):
+		t, err := template.New("output").Parse(strings.TrimPrefix(output, formatTemplate+"="))
+		if err != nil {
+			return f, fmt.Errorf("%w: %s", ErrInvalidFormat, err)
+		}
+		f.format = formatTemplate
+		f.tmpl = t
+	default:
+		return f, fmt.Errorf("%w: %q, use one of text, json, yaml, csv or go-template=...", ErrInvalidFormat, output)
+	}
+
+	return f, nil
+}
+
+// outputFromConfig returns the output format set by the global flags
+func outputFromConfig() (outputFormat, error) {
+	return newOutputFormat(viper.GetString("output"), viper.GetBool("no-headers"))
+}
+
+func (f outputFormat) isText() bool {
+	return f.format == "" || f.format == formatText
+}
+
+func newItemView(id int, i item) itemView {
+	v := itemView{
+		ID:        id,
+		Task:      i.Task,
+		Done:      i.Done,
+		CreatedAt: i.CreatedAt,
+	}
+
+	if i.Done {
+		completedAt := i.CompletedAt
+		v.CompletedAt = &completedAt
+	}
+
+	if !i.Due.IsZero() {
+		due := i.Due
+		v.DueAt = &due
+	}
+
+	return v
+}
+
+// write prints v, a single itemView or a slice of them, in the
+// structured formats
+func (f outputFormat) write(out io.Writer, v any, views []itemView) error {
+	switch f.format {
+	case formatJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case formatYAML:
+		enc := yaml.NewEncoder(out)
+		defer enc.Close()
+		return enc.Encode(v)
+	case formatCSV:
+		return f.writeCSV(out, views)
+	case formatTemplate:
+		return f.tmpl.Execute(out, v)
+	}
+
+	return fmt.Errorf("%w: %q", ErrInvalidFormat, f.format)
+}
+
+func (f outputFormat) writeCSV(out io.Writer, views []itemView) error {
+	w := csv.NewWriter(out)
+	if !f.noHeaders {
+		w.Write([]string{"id", "task", "done", "created_at", "completed_at", "due_at"})
+	}
+
+	for _, v := range views {
+		completedAt, dueAt := "", ""
+		if v.CompletedAt != nil {
+			completedAt = v.CompletedAt.Format(time.RFC3339)
+		}
+		if v.DueAt != nil {
+			dueAt = v.DueAt.Format(time.RFC3339)
+		}
+
+		w.Write([]string{
+			strconv.Itoa(v.ID),
+			v.Task,
+			strconv.FormatBool(v.Done),
+			v.CreatedAt.Format(time.RFC3339),
+			completedAt,
+			dueAt,
+		})
+	}
+
+	w.Flush()
+	return w.Error()
+}
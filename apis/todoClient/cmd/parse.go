@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBulkIDs limits how many items a single ID list can select
+const maxBulkIDs = 1000
+
+// hour used for due dates given without a time of day
+const (
+	defaultDueHour = 9
+	tonightDueHour = 20
+)
+
+// layouts accepted as absolute due dates, dueDateLayout is due at
+// defaultDueHour
+const dueDateLayout = "2006-01-02"
+
+var dueLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04",
+	dueDateLayout,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// joins "5 pm" into "5pm"
+var meridiemSpace = regexp.MustCompile(`(\d) (am|pm)\b`)
+
+// parseDue turns a due date phrase into a time relative to now. It
+// accepts absolute dates (2024-10-20, 2024-10-20 17:00, RFC3339),
+// "in <n> minutes|hours|days|weeks" and a day (today, tonight,
+// tomorrow, friday, this friday, next friday, next week) followed or
+// preceded by a time (5pm, 5:30pm, 17:00, noon, midnight). A bare
+// weekday or "next" weekday is the first one after today, "this"
+// weekday may be today, a time alone is the next time the clock shows
+// it and a date alone is due at 09:00
+func parseDue(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range dueLayouts {
+		t, err := time.ParseInLocation(layout, s, now.Location())
+		if err != nil {
+			continue
+		}
+		if layout == dueDateLayout {
+			t = time.Date(t.Year(), t.Month(), t.Day(), defaultDueHour, 0, 0, 0, now.Location())
+		}
+		return t, nil
+	}
+
+	phrase := strings.ToLower(s)
+
+	invalid := fmt.Errorf("%w: due date %q", ErrInvalid, s)
+	words := strings.Fields(meridiemSpace.ReplaceAllString(phrase, "$1$2"))
+
+	if words[0] == "in" {
+		t, ok := parseRelative(words[1:], now)
+		if !ok {
+			return time.Time{}, invalid
+		}
+		return t, nil
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var (
+		date        time.Time
+		hasDate     bool
+		hour, min   int
+		hasTime     bool
+		defaultHour = defaultDueHour
+	)
+
+	setDate := func(d time.Time) bool {
+		if hasDate {
+			return false
+		}
+		date, hasDate = d, true
+		return true
+	}
+
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		ok := true
+
+		switch {
+		case w == "at" || w == "on":
+			continue
+		case w == "today":
+			ok = setDate(today)
+		case w == "tonight":
+			ok = setDate(today)
+			defaultHour = tonightDueHour
+		case w == "tomorrow":
+			ok = setDate(today.AddDate(0, 0, 1))
+		case w == "next" || w == "this":
+			if i+1 == len(words) {
+				return time.Time{}, invalid
+			}
+			i++
+			if words[i] == "week" && w == "next" {
+				ok = setDate(today.AddDate(0, 0, 7))
+				break
+			}
+			wd, found := weekdays[words[i]]
+			ok = found && setDate(nextWeekday(today, wd, w == "this"))
+		default:
+			if wd, found := weekdays[w]; found {
+				ok = setDate(nextWeekday(today, wd, false))
+				break
+			}
+			if hasTime {
+				return time.Time{}, invalid
+			}
+			hour, min, ok = parseClock(w)
+			hasTime = true
+		}
+
+		if !ok {
+			return time.Time{}, invalid
+		}
+	}
+
+	switch {
+	case !hasDate && !hasTime:
+		return time.Time{}, invalid
+	case !hasDate:
+		t := time.Date(today.Year(), today.Month(), today.Day(), hour, min, 0, 0, now.Location())
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	case !hasTime:
+		hour = defaultHour
+	}
+
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, min, 0, 0, now.Location()), nil
+}
+
+// nextWeekday returns the first day after today falling on wd, or
+// today itself if includeToday is set
+func nextWeekday(today time.Time, wd time.Weekday, includeToday bool) time.Time {
+	days := (int(wd) - int(today.Weekday()) + 7) % 7
+	if days == 0 && !includeToday {
+		days = 7
+	}
+
+	return today.AddDate(0, 0, days)
+}
+
+func parseRelative(words []string, now time.Time) (time.Time, bool) {
+	if len(words) != 2 {
+		return time.Time{}, false
+	}
+
+	n, err := strconv.Atoi(words[0])
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+
+	switch strings.TrimSuffix(words[1], "s") {
+	case "minute", "min":
+		return now.Add(time.Duration(n) * time.Minute), true
+	case "hour", "hr":
+		return now.Add(time.Duration(n) * time.Hour), true
+	case "day":
+		return now.AddDate(0, 0, n), true
+	case "week":
+		return now.AddDate(0, 0, 7*n), true
+	}
+
+	return time.Time{}, false
+}
+
+// parseClock parses a time of day like 5pm, 5:30am, 17:00 or noon
+func parseClock(w string) (int, int, bool) {
+	switch w {
+	case "noon":
+		return 12, 0, true
+	case "midnight":
+		return 0, 0, true
+	}
+
+	meridiem := ""
+	if strings.HasSuffix(w, "am") || strings.HasSuffix(w, "pm") {
+		meridiem = w[len(w)-2:]
+		w = w[:len(w)-2]
+	}
+
+	hs, ms, hasMin := strings.Cut(w, ":")
+	if !hasMin && meridiem == "" {
+		return 0, 0, false
+	}
+
+	h, err := strconv.Atoi(hs)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	m := 0
+	if hasMin {
+		if len(ms) != 2 {
+			return 0, 0, false
+		}
+		if m, err = strconv.Atoi(ms); err != nil {
+			return 0, 0, false
+		}
+	}
+
+	if meridiem != "" {
+		if h < 1 || h > 12 {
+			return 0, 0, false
+		}
+		h %= 12
+		if meridiem == "pm" {
+			h += 12
+		}
+	}
+
+	if h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, 0, false
+	}
+
+	return h, m, true
+}
+
+// parseIDs parses item IDs given as lists and ranges, like 1-5,8 or
+// "1 3 5-7", into a sorted list without duplicates
+func parseIDs(args []string) ([]int, error) {
+	seen := map[int]bool{}
+
+	for _, part := range strings.Split(strings.Join(args, ","), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("%w: id %q", ErrNotNumber, part)
+		}
+
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("%w: id %q", ErrNotNumber, part)
+			}
+		}
+
+		if from < 1 || to < from {
+			return nil, fmt.Errorf("%w: id range %q", ErrInvalid, part)
+		}
+
+		if to-from >= maxBulkIDs {
+			return nil, fmt.Errorf("%w: id range %q selects more than %d items", ErrInvalid, part, maxBulkIDs)
+		}
+
+		for id := from; id <= to; id++ {
+			seen[id] = true
+		}
+
+		if len(seen) > maxBulkIDs {
+			return nil, fmt.Errorf("%w: more than %d ids", ErrInvalid, maxBulkIDs)
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("%w: no ids given", ErrInvalid)
+	}
+
+	ids := make([]int, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	return ids, nil
+}
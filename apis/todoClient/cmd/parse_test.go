@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDue(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2024, time.October, 16, 10, 30, 0, 0, time.UTC)
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2024, time.October, day, hour, min, 0, 0, time.UTC)
+	}
+
+	testCases := []struct {
+		name     string
+		phrase   string
+		exp      time.Time
+		expError error
+	}{
+		{name: "Empty", phrase: "", exp: time.Time{}},
+		{name: "Date", phrase: "2024-10-20", exp: at(20, 9, 0)},
+		{name: "DateTime", phrase: "2024-10-20 17:00", exp: at(20, 17, 0)},
+		{name: "RFC3339", phrase: "2024-10-20T17:00:00Z", exp: at(20, 17, 0)},
+		{name: "Today", phrase: "today", exp: at(16, 9, 0)},
+		{name: "Tonight", phrase: "tonight", exp: at(16, 20, 0)},
+		{name: "TomorrowPM", phrase: "tomorrow 5pm", exp: at(17, 17, 0)},
+		{name: "TomorrowSpacedPM", phrase: "Tomorrow at 5 PM", exp: at(17, 17, 0)},
+		{name: "TimeFirst", phrase: "5:30pm tomorrow", exp: at(17, 17, 30)},
+		{name: "Clock", phrase: "tomorrow 08:15", exp: at(17, 8, 15)},
+		{name: "Noon", phrase: "fri noon", exp: at(18, 12, 0)},
+		{name: "Weekday", phrase: "friday", exp: at(18, 9, 0)},
+		{name: "NextWeekday", phrase: "next friday", exp: at(18, 9, 0)},
+		{name: "NextSameWeekday", phrase: "next wednesday", exp: at(23, 9, 0)},
+		{name: "ThisSameWeekday", phrase: "this wednesday 6pm", exp: at(16, 18, 0)},
+		{name: "NextWeek", phrase: "next week", exp: at(23, 9, 0)},
+		{name: "TimeLater", phrase: "5pm", exp: at(16, 17, 0)},
+		{name: "TimePassed", phrase: "9am", exp: at(17, 9, 0)},
+		{name: "InHours", phrase: "in 2 hours", exp: at(16, 12, 30)},
+		{name: "InDays", phrase: "in 3 days", exp: at(19, 10, 30)},
+		{name: "InOneWeek", phrase: "in 1 week", exp: at(23, 10, 30)},
+		{name: "Unknown", phrase: "someday", expError: ErrInvalid},
+		{name: "TwoDays", phrase: "today tomorrow", expError: ErrInvalid},
+		{name: "TwoTimes", phrase: "5pm 6pm", expError: ErrInvalid},
+		{name: "BadHour", phrase: "tomorrow 13pm", expError: ErrInvalid},
+		{name: "BareNumber", phrase: "tomorrow 5", expError: ErrInvalid},
+		{name: "BadUnit", phrase: "in 2 fortnights", expError: ErrInvalid},
+		{name: "NextNothing", phrase: "next", expError: ErrInvalid},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			due, err := parseDue(tc.phrase, now)
+			if tc.expError != nil {
+				if !errors.Is(err, tc.expError) {
+					t.Fatalf("expected error %q, got %q", tc.expError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+
+			if !due.Equal(tc.exp) {
+				t.Errorf("expected %s, got %s", tc.exp, due)
+			}
+		})
+	}
+}
+
+func TestParseIDs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		exp      []int
+		expError error
+	}{
+		{name: "One", args: []string{"3"}, exp: []int{3}},
+		{name: "RangeAndList", args: []string{"1-5,8"}, exp: []int{1, 2, 3, 4, 5, 8}},
+		{name: "SeveralArgs", args: []string{"8", "1-2", "2"}, exp: []int{1, 2, 8}},
+		{name: "Spaces", args: []string{"1, 3 ,"}, exp: []int{1, 3}},
+		{name: "NotNumber", args: []string{"a"}, expError: ErrNotNumber},
+		{name: "BadRangeEnd", args: []string{"1-x"}, expError: ErrNotNumber},
+		{name: "Negative", args: []string{"-3"}, expError: ErrNotNumber},
+		{name: "Zero", args: []string{"0"}, expError: ErrInvalid},
+		{name: "Reversed", args: []string{"5-2"}, expError: ErrInvalid},
+		{name: "TooMany", args: []string{"1-5000"}, expError: ErrInvalid},
+		{name: "Empty", args: []string{","}, expError: ErrInvalid},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ids, err := parseIDs(tc.args)
+			if tc.expError != nil {
+				if !errors.Is(err, tc.expError) {
+					t.Fatalf("expected error %q, got %q", tc.expError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+
+			if !reflect.DeepEqual(ids, tc.exp) {
+				t.Errorf("expected %v, got %v", tc.exp, ids)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -12,6 +13,15 @@ import (
 var rootCmd = &cobra.Command{
 	Use:   "todoClient",
 	Short: "A todo API client",
+	Long: `A todo API client.
+
+Settings are read from command line flags, TODO_* environment variables
+and the active profile of the config file, in that order. See
+'todoClient config --help' to manage profiles.`,
+
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return initConfig(true)
+	},
 
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
@@ -28,12 +38,30 @@ func Execute() {
 }
 
 func init() {
+	rootCmd.PersistentFlags().String("config", defaultConfigFile(), "Config file")
+	rootCmd.PersistentFlags().StringP("profile", "P", "", "Config profile to use (default is the active profile)")
 	rootCmd.PersistentFlags().String("api-root", "http://localhost:8080", "Todo API URL")
+	rootCmd.PersistentFlags().String("cache-dir", defaultCacheDir(), "Directory for the offline cache and sync queue")
+	rootCmd.PersistentFlags().StringP("output", "o", formatText, "Output format: text, json, yaml, csv or go-template=<template>")
+	rootCmd.PersistentFlags().Bool("no-headers", false, "Don't print headers in csv output")
+	rootCmd.PersistentFlags().Duration("timeout", 10*time.Second, "Timeout for each request")
+	rootCmd.PersistentFlags().Int("retries", 3, "Retries for idempotent requests on transient failures")
+	rootCmd.PersistentFlags().Duration("retry-backoff", 500*time.Millisecond, "Initial backoff between retries")
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Log each request and response")
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
 	replacer := strings.NewReplacer("-", "_")
 	viper.SetEnvKeyReplacer(replacer)
 	viper.SetEnvPrefix("TODO")
 
+	viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
+	viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
 	viper.BindPFlag("api-root", rootCmd.PersistentFlags().Lookup("api-root"))
+	viper.BindPFlag("cache-dir", rootCmd.PersistentFlags().Lookup("cache-dir"))
+	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+	viper.BindPFlag("no-headers", rootCmd.PersistentFlags().Lookup("no-headers"))
+	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("retries", rootCmd.PersistentFlags().Lookup("retries"))
+	viper.BindPFlag("retry-backoff", rootCmd.PersistentFlags().Lookup("retry-backoff"))
+	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
 }
@@ -0,0 +1,128 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:          "sync",
+	Short:        "Replay commands queued while offline",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		skip, err := cmd.Flags().GetBool("skip-conflicts")
+		if err != nil {
+			return err
+		}
+
+		apiRoot := viper.GetString("api-root")
+		s := newOfflineStore(viper.GetString("cache-dir"), apiRoot)
+		return syncAction(os.Stdout, apiRoot, s, skip)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().Bool("skip-conflicts", false, "Drop conflicting commands instead of stopping")
+}
+
+func syncAction(out io.Writer, apiRoot string, s *offlineStore, skipConflicts bool) error {
+	entries, err := s.journal()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		_, err := fmt.Fprintln(out, "Nothing to sync")
+		return err
+	}
+
+	items, err := getAll(apiRoot)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	for len(entries) > 0 {
+		e := entries[0]
+		if err := checkConflict(items, e); err != nil {
+			if !skipConflicts {
+				return stopSync(s, entries, err)
+			}
+
+			fmt.Fprintf(out, "Skipped %s %q: %s\n", e.Op, e.queuedArg(), err)
+			entries = entries[1:]
+			continue
+		}
+
+		if err := replay(apiRoot, e); err != nil {
+			return stopSync(s, entries, err)
+		}
+
+		items = apply(items, e)
+		entries = entries[1:]
+		fmt.Fprintf(out, "Synced %s %q\n", e.Op, e.queuedArg())
+	}
+
+	if err := s.saveJournal(entries); err != nil {
+		return err
+	}
+
+	items, err = getAll(apiRoot)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	return s.saveCache(items)
+}
+
+// stopSync keeps the entries not synced yet in the journal and returns
+// err, along with any error saving them
+func stopSync(s *offlineStore, entries []journalEntry, err error) error {
+	if saveErr := s.saveJournal(entries); saveErr != nil {
+		return fmt.Errorf("%w, and saving the journal failed: %w", err, saveErr)
+	}
+
+	return err
+}
+
+// checkConflict verifies the ID of e still refers to the task it was
+// queued for
+func checkConflict(items []item, e journalEntry) error {
+	if e.Op == opAdd {
+		return nil
+	}
+
+	if e.ID < 1 || e.ID > len(items) {
+		return fmt.Errorf("%w: %s %d: item no longer exists", ErrConflict, e.Op, e.ID)
+	}
+
+	if e.Task != "" && items[e.ID-1].Task != e.Task {
+		return fmt.Errorf("%w: %s %d: expected task %q, found %q", ErrConflict, e.Op, e.ID, e.Task, items[e.ID-1].Task)
+	}
+
+	return nil
+}
+
+func replay(apiRoot string, e journalEntry) error {
+	switch e.Op {
+	case opAdd:
+		return addItem(apiRoot, e.Task, e.Due)
+	case opComplete:
+		return completeItem(apiRoot, e.ID)
+	case opDelete:
+		return deleteItem(apiRoot, e.ID)
+	}
+
+	return fmt.Errorf("%w: unknown operation %q", ErrInvalid, e.Op)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bedminer1/apis/todoClient/app"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tuiCmd represents the tui command
+var tuiCmd = &cobra.Command{
+	Use:          "tui",
+	Short:        "Manage todo items in an interactive terminal UI",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		refresh, err := cmd.Flags().GetDuration("refresh")
+		if err != nil {
+			return err
+		}
+
+		apiRoot := viper.GetString("api-root")
+		return tuiAction(apiRepo{apiRoot: apiRoot}, refresh)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+
+	tuiCmd.Flags().Duration("refresh", 5*time.Second, "Interval to reload the list from the server")
+}
+
+func tuiAction(repo app.Repository, refresh time.Duration) error {
+	a, err := app.New(repo, refresh)
+	if err != nil {
+		return err
+	}
+
+	return a.Run()
+}
+
+// apiRepo implements app.Repository on top of the todo API
+type apiRepo struct {
+	apiRoot string
+}
+
+func (r apiRepo) List() ([]app.Item, error) {
+	items, err := getAll(r.apiRoot)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return []app.Item{}, nil
+		}
+		return nil, err
+	}
+
+	res := make([]app.Item, 0, len(items))
+	for _, i := range items {
+		res = append(res, app.Item{
+			Task:        i.Task,
+			Done:        i.Done,
+			CreatedAt:   i.CreatedAt,
+			CompletedAt: i.CompletedAt,
+			Due:         i.Due,
+		})
+	}
+
+	return res, nil
+}
+
+func (r apiRepo) Add(task string) error {
+	return addItem(r.apiRoot, task, time.Time{})
+}
+
+func (r apiRepo) Complete(id int) error {
+	return completeItem(r.apiRoot, id)
+}
+
+func (r apiRepo) Delete(id int) error {
+	return deleteItem(r.apiRoot, id)
+}
+
+func (r apiRepo) Update(id int, task string) error {
+	return updateItem(r.apiRoot, id, task)
+}
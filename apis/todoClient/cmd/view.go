@@ -4,6 +4,7 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -21,8 +22,14 @@ var viewCmd = &cobra.Command{
 	SilenceUsage: true,
 	Args:         cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := outputFromConfig()
+		if err != nil {
+			return err
+		}
+
 		apiRoot := viper.GetString("api-root")
-		return viewAction(os.Stdout, apiRoot, args[0])
+		s := newOfflineStore(viper.GetString("cache-dir"), apiRoot)
+		return viewAction(os.Stdout, apiRoot, s, f, args[0])
 	},
 }
 
@@ -40,24 +47,46 @@ func init() {
 	// viewCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func viewAction(out io.Writer, apiRoot string, arg string) error {
+func viewAction(out io.Writer, apiRoot string, s *offlineStore, f outputFormat, arg string) error {
 	id, err := strconv.Atoi(arg)
 	if err != nil {
 		return fmt.Errorf("%w: item id must be number", ErrNotNumber)
 	}
 
 	i, err := getOne(apiRoot, id)
+	if errors.Is(err, ErrConnection) {
+		c, cacheErr := s.loadCache()
+		if cacheErr != nil {
+			return err
+		}
+
+		if id < 1 || id > len(c.Results) {
+			return fmt.Errorf("%w: item %d not in cached list", ErrNotFound, id)
+		}
+
+		printOffline(c)
+		return printOne(out, id, c.Results[id-1], f)
+	}
+
 	if err != nil {
 		return err
 	}
 
-	return printOne(out, i)
+	return printOne(out, id, i, f)
 }
 
-func printOne(out io.Writer, i item) error {
+func printOne(out io.Writer, id int, i item, f outputFormat) error {
+	if !f.isText() {
+		v := newItemView(id, i)
+		return f.write(out, v, []itemView{v})
+	}
+
 	w := tabwriter.NewWriter(out, 14, 2, 0, ' ', 0)
 	fmt.Fprintf(w, "Task:\t%s\n", i.Task)
 	fmt.Fprintf(w, "Created at:\t%s\n", i.CreatedAt.Format(timeFormat))
+	if !i.Due.IsZero() {
+		fmt.Fprintf(w, "Due:\t%s\n", i.Due.Format(timeFormat))
+	}
 
 	if i.Done {
 		fmt.Fprintf(w, "Completed:\t%s\n", "Yes")
@@ -5,3 +5,5 @@ go 1.22.1
 replace github.com/bedminer1/chapter1todo => ../../no_cobra
 
 require github.com/bedminer1/chapter1todo v0.0.0-00010101000000-000000000000
+
+require github.com/graphql-go/graphql v0.8.1
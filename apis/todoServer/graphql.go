@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	todo "github.com/bedminer1/chapter1todo"
+	"github.com/graphql-go/graphql"
+)
+
+type gqlItem struct {
+	ID          int       `json:"id"`
+	Task        string    `json:"task"`
+	Done        bool      `json:"done"`
+	CreatedAt   time.Time `json:"createdAt"`
+	CompletedAt time.Time `json:"completedAt"`
+	Due         time.Time `json:"due"`
+}
+
+type gqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// gqlState is the storage shared with the resolvers of a single request
+type gqlState struct {
+	list     *todo.List
+	todoFile string
+	wh       *webhooks
+}
+
+type gqlStateKey struct{}
+
+func newGQLItem(list *todo.List, id int) gqlItem {
+	i := (*list)[id-1]
+	return gqlItem{
+		ID:          id,
+		Task:        i.Task,
+		Done:        i.Done,
+		CreatedAt:   i.CreatedAt,
+		CompletedAt: i.CompletedAt,
+		Due:         i.Due,
+	}
+}
+
+func newSchema() (graphql.Schema, error) {
+	itemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"task":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"done":        &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"createdAt":   &graphql.Field{Type: graphql.DateTime},
+			"completedAt": &graphql.Field{Type: graphql.DateTime},
+			"due":         &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	idArgs := graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"items": &graphql.Field{
+				Type: graphql.NewList(itemType),
+				Args: graphql.FieldConfigArgument{
+					"done":     &graphql.ArgumentConfig{Type: graphql.Boolean},
+					"contains": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolveItems,
+			},
+			"item": &graphql.Field{
+				Type:    itemType,
+				Args:    idArgs,
+				Resolve: resolveItem,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"add": &graphql.Field{
+				Type: itemType,
+				Args: graphql.FieldConfigArgument{
+					"task": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"due":  &graphql.ArgumentConfig{Type: graphql.DateTime},
+				},
+				Resolve: resolveAdd,
+			},
+			"complete": &graphql.Field{
+				Type:    itemType,
+				Args:    idArgs,
+				Resolve: resolveComplete,
+			},
+			"update": &graphql.Field{
+				Type: itemType,
+				Args: graphql.FieldConfigArgument{
+					"id":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"task": &graphql.ArgumentConfig{Type: graphql.String},
+					"done": &graphql.ArgumentConfig{Type: graphql.Boolean},
+				},
+				Resolve: resolveUpdate,
+			},
+			"delete": &graphql.Field{
+				Type:    itemType,
+				Args:    idArgs,
+				Resolve: resolveDelete,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+// graphqlHandler serves GraphQL requests using the same todo file and
+// lock as todoRouter so both APIs see the same list
+func graphqlHandler(todoFile string, l sync.Locker, wh *webhooks) http.HandlerFunc {
+	schema, schemaErr := newSchema()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if schemaErr != nil {
+			replyError(w, r, http.StatusInternalServerError, schemaErr.Error())
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			message := "Method not supported"
+			replyError(w, r, http.StatusMethodNotAllowed, message)
+			return
+		}
+
+		var req gqlRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			message := fmt.Sprintf("Invalid JSON: %q", err)
+			replyError(w, r, http.StatusBadRequest, message)
+			return
+		}
+
+		if req.Query == "" {
+			replyError(w, r, http.StatusBadRequest, "Missing query")
+			return
+		}
+
+		list := &todo.List{}
+		l.Lock()
+		defer l.Unlock()
+
+		if err := list.Get(todoFile); err != nil {
+			replyError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		state := &gqlState{
+			list:     list,
+			todoFile: todoFile,
+			wh:       wh,
+		}
+
+		res := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			OperationName:  req.OperationName,
+			VariableValues: req.Variables,
+			Context:        context.WithValue(r.Context(), gqlStateKey{}, state),
+		})
+
+		replyJSONContent(w, r, http.StatusOK, res)
+	}
+}
+
+func stateFrom(p graphql.ResolveParams) *gqlState {
+	return p.Context.Value(gqlStateKey{}).(*gqlState)
+}
+
+func (s *gqlState) validateID(id int) error {
+	if id < 1 || id > len(*s.list) {
+		return fmt.Errorf("%w: item %d", ErrNotFound, id)
+	}
+
+	return nil
+}
+
+func resolveItems(p graphql.ResolveParams) (interface{}, error) {
+	s := stateFrom(p)
+	done, filterDone := p.Args["done"].(bool)
+	contains, _ := p.Args["contains"].(string)
+
+	items := []gqlItem{}
+	for k := range *s.list {
+		i := newGQLItem(s.list, k+1)
+		if filterDone && i.Done != done {
+			continue
+		}
+		if !strings.Contains(i.Task, contains) {
+			continue
+		}
+		items = append(items, i)
+	}
+
+	return items, nil
+}
+
+func resolveItem(p graphql.ResolveParams) (interface{}, error) {
+	s := stateFrom(p)
+	id := p.Args["id"].(int)
+	if err := s.validateID(id); err != nil {
+		return nil, err
+	}
+
+	return newGQLItem(s.list, id), nil
+}
+
+func resolveAdd(p graphql.ResolveParams) (interface{}, error) {
+	s := stateFrom(p)
+	s.list.Add(p.Args["task"].(string))
+	if due, ok := p.Args["due"].(time.Time); ok {
+		(*s.list)[len(*s.list)-1].Due = due
+	}
+	if err := s.list.Save(s.todoFile); err != nil {
+		return nil, err
+	}
+
+	id := len(*s.list)
+	s.wh.notify(newEvent(eventAdded, s.list, id))
+	return newGQLItem(s.list, id), nil
+}
+
+func resolveComplete(p graphql.ResolveParams) (interface{}, error) {
+	s := stateFrom(p)
+	id := p.Args["id"].(int)
+	if err := s.validateID(id); err != nil {
+		return nil, err
+	}
+
+	s.list.Complete(id)
+	if err := s.list.Save(s.todoFile); err != nil {
+		return nil, err
+	}
+
+	s.wh.notify(newEvent(eventCompleted, s.list, id))
+	return newGQLItem(s.list, id), nil
+}
+
+func resolveUpdate(p graphql.ResolveParams) (interface{}, error) {
+	s := stateFrom(p)
+	id := p.Args["id"].(int)
+	if err := s.validateID(id); err != nil {
+		return nil, err
+	}
+
+	i := &(*s.list)[id-1]
+	if task, ok := p.Args["task"].(string); ok {
+		i.Task = task
+	}
+
+	completed := false
+	if done, ok := p.Args["done"].(bool); ok && done != i.Done {
+		i.Done = done
+		i.CompletedAt = time.Time{}
+		if done {
+			i.CompletedAt = time.Now()
+			completed = true
+		}
+	}
+
+	if err := s.list.Save(s.todoFile); err != nil {
+		return nil, err
+	}
+
+	if completed {
+		s.wh.notify(newEvent(eventCompleted, s.list, id))
+	}
+	return newGQLItem(s.list, id), nil
+}
+
+func resolveDelete(p graphql.ResolveParams) (interface{}, error) {
+	s := stateFrom(p)
+	id := p.Args["id"].(int)
+	if err := s.validateID(id); err != nil {
+		return nil, err
+	}
+
+	item := newGQLItem(s.list, id)
+	ev := newEvent(eventDeleted, s.list, id)
+	s.list.Delete(id)
+	if err := s.list.Save(s.todoFile); err != nil {
+		return nil, err
+	}
+
+	s.wh.notify(ev)
+	return item, nil
+}
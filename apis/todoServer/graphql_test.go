@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type gqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func doGraphQL(t *testing.T, url, query string) gqlResponse {
+	t.Helper()
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(gqlRequest{Query: query}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.Post(url+"/graphql", "application/json", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		t.Fatalf("expected %q, got %q", http.StatusText(http.StatusOK), http.StatusText(r.StatusCode))
+	}
+
+	var resp gqlResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp
+}
+
+func TestGraphQLQuery(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		field    string
+		expTasks []string
+		expError bool
+	}{
+		{
+			name:     "Items",
+			query:    `{ items { id task done } }`,
+			field:    "items",
+			expTasks: []string{"Task number 1.", "Task number 2."},
+		},
+		{
+			name:     "ItemsContains",
+			query:    `{ items(contains: "2") { task } }`,
+			field:    "items",
+			expTasks: []string{"Task number 2."},
+		},
+		{
+			name:     "ItemsDone",
+			query:    `{ items(done: true) { task } }`,
+			field:    "items",
+			expTasks: []string{},
+		},
+		{
+			name:     "Item",
+			query:    `{ item(id: 2) { task } }`,
+			field:    "item",
+			expTasks: []string{"Task number 2."},
+		},
+		{
+			name:     "NotFound",
+			query:    `{ item(id: 500) { task } }`,
+			expError: true,
+		},
+	}
+
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := doGraphQL(t, url, tc.query)
+			if tc.expError {
+				if len(resp.Errors) == 0 {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+
+			if len(resp.Errors) != 0 {
+				t.Fatalf("unexpected error: %q", resp.Errors[0].Message)
+			}
+
+			var items []gqlItem
+			raw := resp.Data[tc.field]
+			if tc.field == "item" {
+				raw = append(append([]byte("["), raw...), ']')
+			}
+			if err := json.Unmarshal(raw, &items); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(items) != len(tc.expTasks) {
+				t.Fatalf("expected %d items, got %d", len(tc.expTasks), len(items))
+			}
+
+			for i, task := range tc.expTasks {
+				if items[i].Task != task {
+					t.Errorf("expected %q, got %q", task, items[i].Task)
+				}
+			}
+		})
+	}
+}
+
+func TestGraphQLMutation(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	testCases := []struct {
+		name     string
+		query    string
+		expTasks []string
+		expDone  []bool
+	}{
+		{
+			name:     "Add",
+			query:    `mutation { add(task: "Task number 3.") { id } }`,
+			expTasks: []string{"Task number 1.", "Task number 2.", "Task number 3."},
+			expDone:  []bool{false, false, false},
+		},
+		{
+			name:     "Complete",
+			query:    `mutation { complete(id: 1) { id } }`,
+			expTasks: []string{"Task number 1.", "Task number 2.", "Task number 3."},
+			expDone:  []bool{true, false, false},
+		},
+		{
+			name:     "Update",
+			query:    `mutation { update(id: 2, task: "Task number two.", done: true) { id } }`,
+			expTasks: []string{"Task number 1.", "Task number two.", "Task number 3."},
+			expDone:  []bool{true, true, false},
+		},
+		{
+			name:     "Delete",
+			query:    `mutation { delete(id: 1) { id } }`,
+			expTasks: []string{"Task number two.", "Task number 3."},
+			expDone:  []bool{true, false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := doGraphQL(t, url, tc.query)
+			if len(resp.Errors) != 0 {
+				t.Fatalf("unexpected error: %q", resp.Errors[0].Message)
+			}
+
+			// the REST API must see the same list
+			r, err := http.Get(url + "/todo")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var list todoResponse
+			if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if len(list.Results) != len(tc.expTasks) {
+				t.Fatalf("expected %d items, got %d", len(tc.expTasks), len(list.Results))
+			}
+
+			for i, item := range list.Results {
+				if item.Task != tc.expTasks[i] {
+					t.Errorf("expected %q, got %q", tc.expTasks[i], item.Task)
+				}
+				if item.Done != tc.expDone[i] {
+					t.Errorf("expected item %d done to be %t", i+1, tc.expDone[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGraphQLDue(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	due := time.Date(2024, time.October, 20, 17, 0, 0, 0, time.UTC)
+	resp := doGraphQL(t, url, `mutation { add(task: "Task number 3.", due: "2024-10-20T17:00:00Z") { id } }`)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("unexpected error: %q", resp.Errors[0].Message)
+	}
+
+	resp = doGraphQL(t, url, `{ item(id: 3) { due } }`)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("unexpected error: %q", resp.Errors[0].Message)
+	}
+
+	var item gqlItem
+	if err := json.Unmarshal(resp.Data["item"], &item); err != nil {
+		t.Fatal(err)
+	}
+
+	if !item.Due.Equal(due) {
+		t.Errorf("expected due %s, got %s", due, item.Due)
+	}
+
+	// the REST API must see the same due date
+	r, err := http.Get(url + "/todo/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+
+	var list todoResponse
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list.Results) != 1 || !list.Results[0].Due.Equal(due) {
+		t.Errorf("expected due %s, got %+v", due, list.Results)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"sync"
+	"time"
 
 	todo "github.com/bedminer1/chapter1todo"
 )
@@ -26,7 +28,7 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 	replyTextContent(w, r, http.StatusOK, content)
 }
 
-func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
+func todoRouter(todoFile string, l sync.Locker, wh *webhooks) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		list := &todo.List{}
 		l.Lock()
@@ -42,7 +44,7 @@ func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
 			case http.MethodGet:
 				getAllHandler(w, r, list)
 			case http.MethodPost:
-				addHandler(w, r, list, todoFile)
+				addHandler(w, r, list, todoFile, wh)
 			default: 
 				message := "Method not supported"
 				replyError(w, r, http.StatusMethodNotAllowed, message)
@@ -64,9 +66,9 @@ func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
 		case http.MethodGet:
 			getOneHandler(w, r, list, id)
 		case http.MethodDelete:
-			deleteHandler(w, r, list, id, todoFile)
+			deleteHandler(w, r, list, id, todoFile, wh)
 		case http.MethodPatch:
-			patchHandler(w, r, list, id, todoFile)
+			patchHandler(w, r, list, id, todoFile, wh)
 		default:
 			message := "Method not supported"
 			replyError(w, r, http.StatusMethodNotAllowed, message)
@@ -88,17 +90,20 @@ func getOneHandler(w http.ResponseWriter, r *http.Request, list *todo.List, id i
 	replyJSONContent(w, r, http.StatusOK, resp)
 }
 
-func deleteHandler(w http.ResponseWriter, r *http.Request, list *todo.List, id int, todoFile string) {
+func deleteHandler(w http.ResponseWriter, r *http.Request, list *todo.List, id int, todoFile string, wh *webhooks) {
+	ev := newEvent(eventDeleted, list, id)
 	list.Delete(id)
 	if err := list.Save(todoFile); err != nil {
 		replyError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	wh.notify(ev)
+
 	replyTextContent(w, r, http.StatusNoContent, "")
 }
 
-func patchHandler(w http.ResponseWriter, r *http.Request, list *todo.List, id int, todoFile string) {
+func patchHandler(w http.ResponseWriter, r *http.Request, list *todo.List, id int, todoFile string, wh *webhooks) {
 	q := r.URL.Query()
 
 	if _, ok := q["complete"]; !ok {
@@ -113,12 +118,15 @@ func patchHandler(w http.ResponseWriter, r *http.Request, list *todo.List, id in
 		return
 	}
 
+	wh.notify(newEvent(eventCompleted, list, id))
+
 	replyTextContent(w, r, http.StatusNoContent, "")
 }
 
-func addHandler(w http.ResponseWriter, r *http.Request, list *todo.List, todoFile string) {
+func addHandler(w http.ResponseWriter, r *http.Request, list *todo.List, todoFile string, wh *webhooks) {
 	item := struct {
-		Task string `json:"task"`
+		Task string    `json:"task"`
+		Due  time.Time `json:"due"`
 	}{}
 
 	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
@@ -128,11 +136,14 @@ func addHandler(w http.ResponseWriter, r *http.Request, list *todo.List, todoFil
 	}
 
 	list.Add(item.Task)
+	(*list)[len(*list)-1].Due = item.Due
 	if err := list.Save(todoFile); err != nil {
 		replyError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	wh.notify(newEvent(eventAdded, list, len(*list)))
+
 	replyTextContent(w, r, http.StatusCreated, "")
 }
 
@@ -151,4 +162,97 @@ func validateID(path string, list *todo.List) (int, error) {
 	}
 
 	return id, nil
-}
\ No newline at end of file
+}
+
+func newEvent(name string, list *todo.List, id int) event {
+	i := (*list)[id-1]
+	return event{
+		Event: name,
+		ID:    id,
+		Task:  i.Task,
+		Done:  i.Done,
+	}
+}
+
+func webhooksRouter(wh *webhooks) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" {
+			switch r.Method {
+			case http.MethodGet:
+				listWebhooksHandler(w, r, wh)
+			case http.MethodPost:
+				addWebhookHandler(w, r, wh)
+			default:
+				message := "Method not supported"
+				replyError(w, r, http.StatusMethodNotAllowed, message)
+			}
+			return
+		}
+
+		id, err := strconv.Atoi(r.URL.Path)
+		if err != nil {
+			replyError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if r.Method != http.MethodDelete {
+			message := "Method not supported"
+			replyError(w, r, http.StatusMethodNotAllowed, message)
+			return
+		}
+
+		if err := wh.Remove(id); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				replyError(w, r, http.StatusNotFound, err.Error())
+				return
+			}
+			replyError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		replyTextContent(w, r, http.StatusNoContent, "")
+	}
+}
+
+func listWebhooksHandler(w http.ResponseWriter, r *http.Request, wh *webhooks) {
+	hooks, err := wh.List()
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// never hand out the signing secrets after registration
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	replyJSONContent(w, r, http.StatusOK, &webhooksResponse{Results: hooks})
+}
+
+func addWebhookHandler(w http.ResponseWriter, r *http.Request, wh *webhooks) {
+	hook := struct {
+		URL    string `json:"url"`
+		Secret string `json:"secret"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		message := fmt.Sprintf("Invalid JSON: %q", err)
+		replyError(w, r, http.StatusBadRequest, message)
+		return
+	}
+
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		message := fmt.Sprintf("%s: Invalid URL: %q", ErrInvalidData, hook.URL)
+		replyError(w, r, http.StatusBadRequest, message)
+		return
+	}
+
+	h, err := wh.Register(hook.URL, hook.Secret)
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	replyJSONContent(w, r, http.StatusCreated, &webhooksResponse{Results: []webhook{h}})
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -12,17 +15,44 @@ func main() {
 	host := flag.String("h", "localhost", "Server host")
 	port := flag.Int("p", 8080, "Server port")
 	todoFile := flag.String("f","todoServer.json", "todo JSON file")
+	hooksFile := flag.String("w", "todoServer.webhooks.json", "webhooks JSON file")
+	deadLetterFile := flag.String("d", "todoServer.deadletter.log", "log file for failed webhook deliveries")
+	retries := flag.Int("r", 3, "webhook delivery retries")
+	backoff := flag.Duration("b", 500*time.Millisecond, "initial webhook retry backoff")
+	shutdown := flag.Duration("s", 10*time.Second, "time given to requests and webhook deliveries to finish on shutdown")
 	flag.Parse()
 
+	wh := newWebhooks(*hooksFile, *deadLetterFile, *retries, *backoff)
+
 	s := &http.Server{
 		Addr: fmt.Sprintf("%s:%d", *host, *port),
-		Handler: newMux(*todoFile),
+		Handler: newMux(*todoFile, wh),
 		ReadTimeout: 10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
-	if err := s.ListenAndServe(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	// stop taking requests, then let the webhook deliveries finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdown)
+	defer cancel()
+
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 	}
+
+	wh.Wait(shutdownCtx)
 }
@@ -7,13 +7,17 @@ import (
 	"sync"
 )
 
-func newMux(todoFile string) http.Handler {
+func newMux(todoFile string, wh *webhooks) http.Handler {
 	m := http.NewServeMux()
 	mu := &sync.Mutex{}
 	m.HandleFunc("/", rootHandler)
-	t := todoRouter(todoFile, mu)
+	t := todoRouter(todoFile, mu, wh)
 	m.Handle("/todo", http.StripPrefix("/todo", t))
 	m.Handle("/todo/", http.StripPrefix("/todo/", t))
+	h := webhooksRouter(wh)
+	m.Handle("/webhooks", http.StripPrefix("/webhooks", h))
+	m.Handle("/webhooks/", http.StripPrefix("/webhooks/", h))
+	m.Handle("/graphql", graphqlHandler(todoFile, mu, wh))
 
 	return m
 }
@@ -24,7 +28,7 @@ func replyTextContent(w http.ResponseWriter, r *http.Request, status int, conten
 	w.Write([]byte(content))
 }
 
-func replyJSONContent(w http.ResponseWriter, r *http.Request, status int, resp *todoResponse) {
+func replyJSONContent(w http.ResponseWriter, r *http.Request, status int, resp any) {
 	body, err := json.Marshal(resp)
 	if err != nil {
 		replyError(w, r, http.StatusInternalServerError, err.Error())
@@ -11,6 +11,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	todo "github.com/bedminer1/chapter1todo"
 )
@@ -23,7 +24,8 @@ func setupAPI(t *testing.T) (string, func()) {
 		t.Fatal(err)
 	}
 
-	ts := httptest.NewServer(newMux(tempTodoFile.Name()))
+	wh := newWebhooks(tempTodoFile.Name()+".webhooks", tempTodoFile.Name()+".deadletter", 0, 0)
+	ts := httptest.NewServer(newMux(tempTodoFile.Name(), wh))
 
 	for i := 1; i < 3; i++ {
 		var body bytes.Buffer
@@ -50,6 +52,7 @@ func setupAPI(t *testing.T) (string, func()) {
 	return ts.URL, func() {
 		ts.Close()
 		os.Remove(tempTodoFile.Name())
+		os.Remove(wh.hooksFile)
 	}
 }
 
@@ -143,12 +146,15 @@ func TestAdd(t *testing.T) {
 	defer cleanup()
 
 	taskName := "Task number 3."
+	due := time.Date(2024, time.October, 20, 17, 0, 0, 0, time.UTC)
 	t.Run("Add", func(t *testing.T) {
 		var body bytes.Buffer
 		item := struct {
-			Task string `json:"task"`
+			Task string    `json:"task"`
+			Due  time.Time `json:"due"`
 		}{
 			Task: taskName,
+			Due:  due,
 		}
 
 		if err := json.NewEncoder(&body).Encode(item); err != nil {
@@ -184,6 +190,10 @@ func TestAdd(t *testing.T) {
 		if resp.Results[0].Task != taskName {
 			t.Error("taskName does not match")
 		}
+
+		if !resp.Results[0].Due.Equal(due) {
+			t.Errorf("expected due %s, got %s", due, resp.Results[0].Due)
+		}
 	})
 }
 
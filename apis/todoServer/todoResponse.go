@@ -23,4 +23,8 @@ func (r *todoResponse) MarshalJSON() ([]byte, error) {
 	}
 
 	return json.Marshal(resp)
-}
\ No newline at end of file
+}
+
+type webhooksResponse struct {
+	Results []webhook `json:"results"`
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const signatureHeader = "X-Todo-Signature"
+
+// event types sent to webhooks
+const (
+	eventAdded     = "added"
+	eventCompleted = "completed"
+	eventDeleted   = "deleted"
+)
+
+type webhook struct {
+	ID     int    `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// event is the JSON payload POSTed to every registered webhook
+type event struct {
+	Event string `json:"event"`
+	ID    int    `json:"id"`
+	Task  string `json:"task"`
+	Done  bool   `json:"done"`
+	Date  int64  `json:"date"`
+}
+
+// webhooks keeps the registered webhooks in hooksFile and delivers
+// events to them, logging failed deliveries to deadLetterFile
+type webhooks struct {
+	hooksFile      string
+	deadLetterFile string
+	retries        int
+	backoff        time.Duration
+	client         *http.Client
+
+	// ctx is cancelled to stop the deliveries left when Wait gives up
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu sync.Mutex
+	wg sync.WaitGroup
+}
+
+func newWebhooks(hooksFile, deadLetterFile string, retries int, backoff time.Duration) *webhooks {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &webhooks{
+		hooksFile:      hooksFile,
+		deadLetterFile: deadLetterFile,
+		retries:        retries,
+		backoff:        backoff,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// load reads the webhooks from hooksFile, caller must hold wh.mu
+func (wh *webhooks) load() ([]webhook, error) {
+	hooks := []webhook{}
+	f, err := os.ReadFile(wh.hooksFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return hooks, nil
+		}
+		return nil, err
+	}
+
+	if len(f) == 0 {
+		return hooks, nil
+	}
+
+	if err := json.Unmarshal(f, &hooks); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// save writes the webhooks to hooksFile, caller must hold wh.mu
+func (wh *webhooks) save(hooks []webhook) error {
+	js, err := json.Marshal(hooks)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(wh.hooksFile, js, 0600)
+}
+
+func (wh *webhooks) List() ([]webhook, error) {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	return wh.load()
+}
+
+// Register adds a new webhook, generating a secret if none is given
+func (wh *webhooks) Register(url, secret string) (webhook, error) {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	hooks, err := wh.load()
+	if err != nil {
+		return webhook{}, err
+	}
+
+	if secret == "" {
+		if secret, err = newSecret(); err != nil {
+			return webhook{}, err
+		}
+	}
+
+	id := 1
+	for _, h := range hooks {
+		if h.ID >= id {
+			id = h.ID + 1
+		}
+	}
+
+	h := webhook{
+		ID:     id,
+		URL:    url,
+		Secret: secret,
+	}
+	hooks = append(hooks, h)
+
+	return h, wh.save(hooks)
+}
+
+func (wh *webhooks) Remove(id int) error {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	hooks, err := wh.load()
+	if err != nil {
+		return err
+	}
+
+	for i, h := range hooks {
+		if h.ID == id {
+			hooks = append(hooks[:i], hooks[i+1:]...)
+			return wh.save(hooks)
+		}
+	}
+
+	return fmt.Errorf("%w: webhook %d", ErrNotFound, id)
+}
+
+// notify delivers ev to every registered webhook in the background
+func (wh *webhooks) notify(ev event) {
+	ev.Date = time.Now().Unix()
+
+	hooks, err := wh.List()
+	if err != nil {
+		wh.deadLetter(webhook{}, ev, err)
+		return
+	}
+
+	for _, h := range hooks {
+		wh.wg.Add(1)
+		go func(h webhook) {
+			defer wh.wg.Done()
+			if err := wh.deliver(h, ev); err != nil {
+				wh.deadLetter(h, ev, err)
+			}
+		}(h)
+	}
+}
+
+// Wait blocks until all pending deliveries are finished. When ctx is
+// done first, the deliveries left are stopped, including the retries
+// waiting for their backoff, and logged to deadLetterFile
+func (wh *webhooks) Wait(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		wh.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	wh.cancel()
+	<-done
+}
+
+// deliver POSTs the signed event to h, retrying with exponential backoff
+func (wh *webhooks) deliver(h webhook, ev event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	delay := wh.backoff
+	for attempt := 0; ; attempt++ {
+		err = wh.post(h, body)
+		if err == nil || attempt >= wh.retries {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-wh.ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w, retries stopped on shutdown", err)
+		}
+		delay *= 2
+	}
+}
+
+func (wh *webhooks) post(h webhook, body []byte) error {
+	req, err := http.NewRequestWithContext(wh.ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(signatureHeader, "sha256="+sign(h.Secret, body))
+
+	r, err := wh.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return fmt.Errorf("unexpected status: %s", r.Status)
+	}
+
+	return nil
+}
+
+// deadLetter appends a failed delivery to deadLetterFile
+func (wh *webhooks) deadLetter(h webhook, ev event, deliveryErr error) {
+	entry := struct {
+		Date  int64  `json:"date"`
+		URL   string `json:"url"`
+		Event event  `json:"event"`
+		Error string `json:"error"`
+	}{
+		Date:  time.Now().Unix(),
+		URL:   h.URL,
+		Event: ev,
+		Error: deliveryErr.Error(),
+	}
+
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	f, err := os.OpenFile(wh.deadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("dead letter: %s", err)
+		return
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(entry); err != nil {
+		log.Printf("dead letter: %s", err)
+	}
+}
+
+// sign returns the hex encoded HMAC-SHA256 of body using secret
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupWebhooksAPI(t *testing.T, retries int) (string, *webhooks, func()) {
+	t.Helper()
+
+	tempTodoFile, err := os.CreateTemp("", "todotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wh := newWebhooks(tempTodoFile.Name()+".webhooks", tempTodoFile.Name()+".deadletter", retries, time.Millisecond)
+	ts := httptest.NewServer(newMux(tempTodoFile.Name(), wh))
+
+	return ts.URL, wh, func() {
+		ts.Close()
+		os.Remove(tempTodoFile.Name())
+		os.Remove(wh.hooksFile)
+		os.Remove(wh.deadLetterFile)
+	}
+}
+
+// receiver is a local webhook endpoint that records the events it gets
+type receiver struct {
+	mu       sync.Mutex
+	attempts int
+	events   []event
+	failures int
+	secret   string
+	t        *testing.T
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.attempts++
+	if rc.attempts <= rc.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rc.t.Error(err)
+		return
+	}
+
+	expSig := "sha256=" + sign(rc.secret, body)
+	if !hmac.Equal([]byte(expSig), []byte(r.Header.Get(signatureHeader))) {
+		rc.t.Errorf("invalid signature %q", r.Header.Get(signatureHeader))
+	}
+
+	var ev event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		rc.t.Error(err)
+	}
+	rc.events = append(rc.events, ev)
+}
+
+func registerWebhook(t *testing.T, url, hookURL, secret string) webhook {
+	t.Helper()
+
+	var body bytes.Buffer
+	item := struct {
+		URL    string `json:"url"`
+		Secret string `json:"secret"`
+	}{
+		URL:    hookURL,
+		Secret: secret,
+	}
+	if err := json.NewEncoder(&body).Encode(item); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.Post(url+"/webhooks", "application/json", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusCreated {
+		t.Fatalf("Status %d: failed to register webhook", r.StatusCode)
+	}
+
+	var resp webhooksResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Results) != 1 {
+		t.Fatalf("expected 1 webhook, got %d", len(resp.Results))
+	}
+
+	return resp.Results[0]
+}
+
+func addTask(t *testing.T, url, task string) {
+	t.Helper()
+
+	body := strings.NewReader(fmt.Sprintf("{\"task\":%q}", task))
+	r, err := http.Post(url+"/todo", "application/json", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+
+	if r.StatusCode != http.StatusCreated {
+		t.Fatalf("Status %d: failed to add item", r.StatusCode)
+	}
+}
+
+func TestWebhooksEndpoints(t *testing.T) {
+	url, _, cleanup := setupWebhooksAPI(t, 0)
+	defer cleanup()
+
+	t.Run("Register", func(t *testing.T) {
+		h := registerWebhook(t, url, "http://localhost:9999/hook", "")
+		if h.ID != 1 {
+			t.Errorf("expected id 1, got %d", h.ID)
+		}
+		if h.Secret == "" {
+			t.Error("expected a generated secret")
+		}
+	})
+
+	t.Run("InvalidURL", func(t *testing.T) {
+		body := strings.NewReader(`{"url":"localhost"}`)
+		r, err := http.Post(url+"/webhooks", "application/json", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+
+		if r.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected %q, got %q", http.StatusText(http.StatusBadRequest), http.StatusText(r.StatusCode))
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r, err := http.Get(url + "/webhooks")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+
+		var resp webhooksResponse
+		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(resp.Results) != 1 {
+			t.Fatalf("expected 1 webhook, got %d", len(resp.Results))
+		}
+		if resp.Results[0].Secret != "" {
+			t.Error("expected secret to be hidden")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		for _, expCode := range []int{http.StatusNoContent, http.StatusNotFound} {
+			req, err := http.NewRequest(http.MethodDelete, url+"/webhooks/1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if r.StatusCode != expCode {
+				t.Errorf("expected %q, got %q", http.StatusText(expCode), http.StatusText(r.StatusCode))
+			}
+		}
+	})
+}
+
+func TestWebhooksDelivery(t *testing.T) {
+	testCases := []struct {
+		name        string
+		retries     int
+		failures    int
+		expAttempts int
+		expEvents   []string
+		expDead     int
+	}{
+		{
+			name:        "Delivered",
+			retries:     0,
+			expAttempts: 3,
+			expEvents:   []string{eventAdded, eventCompleted, eventDeleted},
+		},
+		{
+			name:        "Retried",
+			retries:     2,
+			failures:    2,
+			expAttempts: 5,
+			expEvents:   []string{eventAdded, eventCompleted, eventDeleted},
+		},
+		{
+			name:        "DeadLetter",
+			retries:     1,
+			failures:    10,
+			expAttempts: 6,
+			expDead:     3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url, wh, cleanup := setupWebhooksAPI(t, tc.retries)
+			defer cleanup()
+
+			rc := &receiver{failures: tc.failures, secret: "s3cr3t", t: t}
+			hs := httptest.NewServer(rc)
+			defer hs.Close()
+
+			registerWebhook(t, url, hs.URL, rc.secret)
+
+			// deliveries run in the background, wait for each one to
+			// keep the events in order
+			addTask(t, url, "Task number 1.")
+			wh.Wait(context.Background())
+
+			for _, method := range []string{http.MethodPatch, http.MethodDelete} {
+				req, err := http.NewRequest(method, url+"/todo/1?complete", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				r, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.Body.Close()
+				wh.Wait(context.Background())
+			}
+
+			if rc.attempts != tc.expAttempts {
+				t.Errorf("expected %d attempts, got %d", tc.expAttempts, rc.attempts)
+			}
+
+			if len(rc.events) != len(tc.expEvents) {
+				t.Fatalf("expected %d events, got %d", len(tc.expEvents), len(rc.events))
+			}
+
+			for i, ev := range rc.events {
+				if ev.Event != tc.expEvents[i] {
+					t.Errorf("expected event %q, got %q", tc.expEvents[i], ev.Event)
+				}
+				if ev.ID != 1 || ev.Task != "Task number 1." {
+					t.Errorf("unexpected event payload: %+v", ev)
+				}
+			}
+
+			dead, err := os.ReadFile(wh.deadLetterFile)
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+
+			if lines := bytes.Count(dead, []byte("\n")); lines != tc.expDead {
+				t.Errorf("expected %d dead letters, got %d", tc.expDead, lines)
+			}
+		})
+	}
+}
+
+func TestWebhooksWaitShutdown(t *testing.T) {
+	url, wh, cleanup := setupWebhooksAPI(t, 3)
+	defer cleanup()
+
+	// every retry waits a minute, longer than the shutdown allows
+	wh.backoff = time.Minute
+
+	rc := &receiver{failures: 10, secret: "s3cr3t", t: t}
+	hs := httptest.NewServer(rc)
+	defer hs.Close()
+
+	registerWebhook(t, url, hs.URL, rc.secret)
+	addTask(t, url, "Task number 1.")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	wh.Wait(ctx)
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected Wait to stop the retries on shutdown, took %s", d)
+	}
+
+	// the delivery stopped isn't lost
+	dead, err := os.ReadFile(wh.deadLetterFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(dead, []byte("retries stopped on shutdown")) {
+		t.Errorf("expected a dead letter for the stopped delivery, got %q", dead)
+	}
+}
@@ -2,15 +2,26 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/bedminer1/cobra/pScan/history"
 	"github.com/bedminer1/cobra/pScan/scan"
+	"github.com/spf13/cobra"
 )
 
 func setup(t *testing.T, hosts []string, initList bool) (string, func()) {
@@ -53,11 +64,13 @@ func TestHostActions(t *testing.T) {
 			args: hosts,
 			expectedOut: "Added host: host1\nAdded host: host2\nAdded host: host3\n",
 			initList: false,
-			actionFunction: addAction,
+			actionFunction: func(out io.Writer, hostsFile string, args []string) error {
+				return addAction(out, hostsFile, args, scan.HostInfo{})
+			},
 		},
 		{
 			name: "ListAction",
-			expectedOut: "host1\nhost2\nhost3\n",
+			expectedOut: "host1\nhost2\nhost3\n3 entries, 3 hosts\n",
 			initList: true,
 			actionFunction: listAction,
 		},
@@ -113,15 +126,17 @@ func TestIntegration(t *testing.T) {
 	}
 	expectedOut += strings.Join(hosts, "\n")
 	expectedOut += fmt.Sprintln()
+	expectedOut += fmt.Sprintln("3 entries, 3 hosts")
 	expectedOut += fmt.Sprintf("Deleted host: %s\n", delHost)
 	expectedOut += strings.Join(hostsEnd, "\n")
 	expectedOut += fmt.Sprintln()
+	expectedOut += fmt.Sprintln("2 entries, 2 hosts")
 	for _, v := range hostsEnd {
 		expectedOut += fmt.Sprintf("%s: Host not found\n", v)
 		expectedOut += fmt.Sprintln()
 	}
 
-	if err := addAction(&out, tf, hosts); err != nil {
+	if err := addAction(&out, tf, hosts, scan.HostInfo{}); err != nil {
 		t.Fatalf("Unexpected error: %q\n", err)
 	}
 
@@ -137,7 +152,7 @@ func TestIntegration(t *testing.T) {
 		t.Fatalf("Unexpected error: %q\n", err)
 	}
 
-	if err := scanAction(&out, tf, nil); err != nil {
+	if err := scanAction(context.Background(), &out, scanConfig{hostsFile: tf, output: outputText}); err != nil {
 		t.Fatalf("Unexpected error: %q\n", err)
 	}
 
@@ -181,20 +196,1540 @@ func TestScanAction(t *testing.T) {
 		}
 	}
 
-	expectedOut := fmt.Sprintln("localhost:")
-	expectedOut += fmt.Sprintf("\t%d: open\n", ports[0])
-	expectedOut += fmt.Sprintf("\t%d: closed\n", ports[1])
-	expectedOut += fmt.Sprintln()
-	expectedOut += fmt.Sprintln("unknownhostoutthere: Host not found")
-	expectedOut += fmt.Sprintln()
+	// latencies vary, match them with a pattern
+	latency := `\(\d+(\.\d+)?(ns|µs|ms|s)\)`
+	// localhost may resolve to IPv4, IPv6 or both
+	expectedOut := `localhost \([0-9a-f.:, ]+\):\n`
+	expectedOut += fmt.Sprintf("\t%d: open %s\n", ports[0], latency)
+	expectedOut += fmt.Sprintf("\t%d: closed %s\n", ports[1], latency)
+	expectedOut += "\n"
+	expectedOut += regexp.QuoteMeta("unknownhostoutthere: Host not found\n")
+	expectedOut += "\n"
 	
 	var out bytes.Buffer
 
-	if err := scanAction(&out, tf, ports); err != nil {
+	if err := scanAction(context.Background(), &out, scanConfig{hostsFile: tf, sel: selection{ports: ports}, output: outputText}); err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	if !regexp.MustCompile("^" + expectedOut + "$").MatchString(out.String()) {
+		t.Errorf("expected output matching: %q, got %q\n", expectedOut, out.String())
+	}
+}
+func TestScanPorts(t *testing.T) {
+	testCases := []struct {
+		name      string
+		ports     string
+		exclude   string
+		top       int
+		expect    []int
+		expectErr error
+	}{
+		{name: "Spec", ports: "22,80-82", expect: []int{22, 80, 81, 82}},
+		{name: "Exclude", ports: "20-25", exclude: "21-23", expect: []int{20, 24, 25}},
+		{name: "Top", ports: "22", top: 2, expect: []int{80, 23}},
+		{name: "TopExclude", top: 3, exclude: "23", expect: []int{80, 443}},
+		{name: "AllExcluded", ports: "web", exclude: "web", expectErr: scan.ErrInvalidPort},
+		{name: "InvalidExclude", ports: "22", exclude: "x", expectErr: scan.ErrInvalidPort},
+		{name: "InvalidTop", top: 500, expectErr: scan.ErrInvalidPort},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ports, err := scanPorts(tc.ports, tc.exclude, tc.top)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Fatalf("Expected error %q, got %q instead\n", tc.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			if !reflect.DeepEqual(ports, tc.expect) {
+				t.Errorf("Expected ports %v, got %v instead\n", tc.expect, ports)
+			}
+		})
+	}
+}
+
+func TestScanActionUDP(t *testing.T) {
+	tf, cleanup := setup(t, []string{"127.0.0.1"}, true)
+	defer cleanup()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 512)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		conn.WriteTo(buf[:n], addr)
+	}()
+
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	expectedOut := fmt.Sprintf("^127\\.0\\.0\\.1:\n\t%d/udp: open \\(.+\\)\n\n$", port)
+
+	var out bytes.Buffer
+	if err := scanAction(context.Background(), &out, scanConfig{
+		hostsFile: tf,
+		sel:       selection{ports: []int{port}, fixed: true},
+		opts:      scan.Options{UDP: true},
+		output:    outputText,
+	}); err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	if !regexp.MustCompile(expectedOut).MatchString(out.String()) {
+		t.Errorf("expected output matching: %q, got %q\n", expectedOut, out.String())
+	}
+}
+
+func TestPrintResults(t *testing.T) {
+	results := []scan.Results{
+		{
+			Host: "host1",
+			PortStates: []scan.PortState{
+				{Port: 22, Proto: scan.ProtoTCP, State: scan.StateOpen, Latency: 1500 * time.Microsecond, Service: "ssh", Version: "OpenSSH_9.6"},
+				{Port: 80, Proto: scan.ProtoTCP, State: scan.StateOpen, Latency: 2 * time.Millisecond, Service: "http"},
+				{Port: 23, Proto: scan.ProtoTCP, State: scan.StateClosed, Latency: 200 * time.Microsecond},
+				{Port: 24, Proto: scan.ProtoTCP, State: scan.StateFiltered, Latency: time.Second},
+				{Port: 25, Proto: scan.ProtoTCP, State: scan.StateError, Err: errors.New("too many open files")},
+				{Port: 53, Proto: scan.ProtoUDP, State: scan.StateOpenFiltered, Latency: time.Second},
+			},
+		},
+		{Host: "host2", Down: true},
+	}
+
+	expectedOut := "host1:\n" +
+		"\t22: open (1.5ms) ssh OpenSSH_9.6\n" +
+		"\t80: open (2ms) http\n" +
+		"\t23: closed (200µs)\n" +
+		"\t24: filtered\n" +
+		"\t25: error (too many open files)\n" +
+		"\t53/udp: open|filtered\n\n" +
+		"host2: Host down\n\n"
+
+	var out bytes.Buffer
+	if err := printResults(&out, results); err != nil {
 		t.Fatalf("Unexpected error: %s\n", err)
 	}
 
 	if out.String() != expectedOut {
 		t.Errorf("expected output: %q, got %q\n", expectedOut, out.String())
 	}
-}
\ No newline at end of file
+}
+
+func TestHostHeader(t *testing.T) {
+	testCases := []struct {
+		name    string
+		results scan.Results
+		expect  string
+	}{
+		{"Address", scan.Results{Host: "10.0.0.1", Addrs: []string{"10.0.0.1"}}, "10.0.0.1"},
+		{"Name", scan.Results{Host: "host1", Addrs: []string{"10.0.0.1", "::1"}}, "host1 (10.0.0.1, ::1)"},
+		{"OneAddr", scan.Results{Host: "host1", Addrs: []string{"10.0.0.1", "::1"}, Addr: "::1"}, "host1 (::1)"},
+		{"Names", scan.Results{Host: "10.0.0.1", Addrs: []string{"10.0.0.1"}, Addr: "10.0.0.1", Names: []string{"host1", "www"}}, "10.0.0.1 [host1, www]"},
+		{"NotFound", scan.Results{Host: "host1", NotFound: true}, "host1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if h := hostHeader(tc.results); h != tc.expect {
+				t.Errorf("Expected %q, got %q instead\n", tc.expect, h)
+			}
+		})
+	}
+}
+
+func TestWriteResults(t *testing.T) {
+	results := []scan.Results{
+		{
+			Host:  "localhost",
+			Addrs: []string{"127.0.0.1", "::1"},
+			PortStates: []scan.PortState{
+				{Port: 22, Proto: scan.ProtoTCP, State: scan.StateOpen, Latency: 1500 * time.Microsecond, Service: "ssh", Version: "OpenSSH_9.6"},
+				{Port: 23, Proto: scan.ProtoTCP, State: scan.StateFiltered, Latency: time.Second},
+			},
+		},
+		{
+			Host:  "10.0.0.9",
+			Addrs: []string{"10.0.0.9"},
+			Down:  true,
+		},
+		{
+			Host:     "unknownhostoutthere",
+			NotFound: true,
+		},
+	}
+
+	start := time.Date(2024, time.September, 2, 10, 0, 0, 0, time.UTC)
+	info := scanInfo{
+		Args:  []string{"pScan", "scan", "-p", "22,23", "-o", "xml"},
+		Ports: []int{22, 23},
+		Start: start,
+		End:   start.Add(1500 * time.Millisecond),
+	}
+
+	testCases := []struct {
+		name        string
+		output      string
+		expectedOut string
+		expectErr   error
+	}{
+		{
+			name:   "JSON",
+			output: outputJSON,
+			expectedOut: `[
+  {
+    "host": "localhost",
+    "addresses": [
+      "127.0.0.1",
+      "::1"
+    ],
+    "not_found": false,
+    "ports": [
+      {
+        "port": 22,
+        "protocol": "tcp",
+        "state": "open",
+        "latency_ms": 1.5,
+        "service": "ssh",
+        "version": "OpenSSH_9.6"
+      },
+      {
+        "port": 23,
+        "protocol": "tcp",
+        "state": "filtered",
+        "latency_ms": 1000
+      }
+    ]
+  },
+  {
+    "host": "10.0.0.9",
+    "addresses": [
+      "10.0.0.9"
+    ],
+    "not_found": false,
+    "down": true,
+    "ports": []
+  },
+  {
+    "host": "unknownhostoutthere",
+    "not_found": true,
+    "ports": []
+  }
+]
+`,
+		},
+		{
+			name:   "CSV",
+			output: outputCSV,
+			expectedOut: "host,addresses,port,protocol,state,latency_ms,service,version,error\n" +
+				"localhost,127.0.0.1 ::1,22,tcp,open,1.5,ssh,OpenSSH_9.6,\n" +
+				"localhost,127.0.0.1 ::1,23,tcp,filtered,1000,,,\n" +
+				"10.0.0.9,10.0.0.9,,,down,,,,\n" +
+				"unknownhostoutthere,,,,not found,,,,\n",
+		},
+		{
+			name:   "XML",
+			output: outputXML,
+			expectedOut: `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE nmaprun>
+<nmaprun scanner="pScan" args="pScan scan -p 22,23 -o xml" start="1725271200" startstr="Mon Sep  2 10:00:00 2024" version="0.1" xmloutputversion="1.05">
+  <scaninfo type="connect" protocol="tcp" numservices="2" services="22,23"></scaninfo>
+  <host>
+    <status state="up" reason="user-set"></status>
+    <address addr="127.0.0.1" addrtype="ipv4"></address>
+    <address addr="::1" addrtype="ipv6"></address>
+    <hostnames>
+      <hostname name="localhost" type="user"></hostname>
+    </hostnames>
+    <ports>
+      <port protocol="tcp" portid="22">
+        <state state="open" reason="syn-ack" rtt="1500"></state>
+        <service name="ssh" product="OpenSSH_9.6" method="probed" conf="10"></service>
+      </port>
+      <port protocol="tcp" portid="23">
+        <state state="filtered" reason="no-response" rtt="1000000"></state>
+      </port>
+    </ports>
+  </host>
+  <host>
+    <status state="down" reason="no-response"></status>
+    <address addr="10.0.0.9" addrtype="ipv4"></address>
+    <hostnames>
+      <hostname name="10.0.0.9" type="user"></hostname>
+    </hostnames>
+  </host>
+  <host>
+    <status state="down" reason="no-dns"></status>
+    <hostnames>
+      <hostname name="unknownhostoutthere" type="user"></hostname>
+    </hostnames>
+  </host>
+  <runstats>
+    <finished time="1725271201" timestr="Mon Sep  2 10:00:01 2024" elapsed="1.5" exit="success"></finished>
+    <hosts up="1" down="2" total="3"></hosts>
+  </runstats>
+</nmaprun>
+`,
+		},
+		{
+			name:      "Invalid",
+			output:    "yaml",
+			expectErr: ErrInvalidOutput,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := writeResults(&out, tc.output, info, results)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Fatalf("Expected error %q, got %q instead\n", tc.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			if out.String() != tc.expectedOut {
+				t.Errorf("expected output: %q, got %q\n", tc.expectedOut, out.String())
+			}
+		})
+	}
+
+	t.Run("Interrupted", func(t *testing.T) {
+		info := info
+		info.Err = context.Canceled
+
+		expected := map[string]string{
+			outputXML:  `exit="error" errormsg="scan interrupted: context canceled"`,
+			outputJSON: `"interrupted": true`,
+		}
+
+		for output, e := range expected {
+			var out bytes.Buffer
+			if err := writeResults(&out, output, info, results); err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			if n := strings.Count(out.String(), e); n == 0 || output == outputJSON && n != len(results) {
+				t.Errorf("%s: expected output to contain %q, got %q\n", output, e, out.String())
+			}
+		}
+	})
+}
+
+func TestWriteResultsTLS(t *testing.T) {
+	results := []scan.Results{
+		{
+			Host:  "host1",
+			Addrs: []string{"10.0.0.1"},
+			PortStates: []scan.PortState{
+				{Port: 22, Proto: scan.ProtoTCP, State: scan.StateOpen, Latency: time.Millisecond},
+				{
+					Port: 443, Proto: scan.ProtoTCP, State: scan.StateOpen, Latency: time.Millisecond,
+					TLS: &scan.TLSInfo{
+						Version:     "TLS 1.3",
+						Cipher:      "TLS_AES_128_GCM_SHA256",
+						Subject:     "CN=web.example.com",
+						Issuer:      "CN=Example CA",
+						SANs:        []string{"web.example.com", "www.example.com"},
+						NotAfter:    time.Date(2024, time.September, 20, 12, 0, 0, 0, time.UTC),
+						Expiring:    true,
+						HostnameErr: "x509: certificate is valid for web.example.com, www.example.com, not host1",
+					},
+				},
+			},
+		},
+	}
+
+	info := scanInfo{Ports: []int{22, 443}, TLS: true}
+
+	testCases := []struct {
+		output string
+		expect []string
+	}{
+		{
+			outputText,
+			[]string{"host1 (10.0.0.1):\n" +
+				"\t22: open (1ms)\n" +
+				"\t443: open (1ms)\n" +
+				"\tTLS on port 443: TLS 1.3, TLS_AES_128_GCM_SHA256\n" +
+				"\t\tsubject: CN=web.example.com\n" +
+				"\t\tissuer: CN=Example CA\n" +
+				"\t\tSANs: web.example.com, www.example.com\n" +
+				"\t\texpires: 2024-09-20 12:00:00 UTC\n" +
+				"\t\twarning: certificate expiring soon\n" +
+				"\t\twarning: hostname mismatch: x509: certificate is valid for web.example.com, www.example.com, not host1\n\n"},
+		},
+		{
+			outputJSON,
+			[]string{
+				`"tls": [`,
+				`"port": 443,`,
+				`"sans": [`,
+				`"not_after": "2024-09-20T12:00:00Z",`,
+				`"expiring": true,`,
+				`"hostname_valid": false,`,
+				`"certificate expiring soon",`,
+			},
+		},
+		{
+			outputCSV,
+			[]string{
+				"host,addresses,port,protocol,state,latency_ms,service,version,error," +
+					"tls_version,tls_cipher,cert_subject,cert_issuer,cert_sans,cert_expiry,cert_warnings\n" +
+					"host1,10.0.0.1,22,tcp,open,1,,,,,,,,,,\n" +
+					"host1,10.0.0.1,443,tcp,open,1,,,,TLS 1.3,TLS_AES_128_GCM_SHA256,CN=web.example.com,CN=Example CA," +
+					"web.example.com www.example.com,2024-09-20T12:00:00Z,\"certificate expiring soon; hostname mismatch: " +
+					"x509: certificate is valid for web.example.com, www.example.com, not host1\"\n",
+			},
+		},
+		{
+			outputXML,
+			[]string{
+				`<script id="ssl-cert" output="Subject: CN=web.example.com&#xA;Subject Alternative Name: web.example.com, www.example.com&#xA;Issuer: CN=Example CA&#xA;Not valid after:  2024-09-20T12:00:00">`,
+				`<elem key="notAfter">2024-09-20T12:00:00</elem>`,
+				`<elem key="warning">certificate expiring soon</elem>`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.output, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := writeResults(&out, tc.output, info, results); err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			for _, e := range tc.expect {
+				if !strings.Contains(out.String(), e) {
+					t.Errorf("Expected output containing %q, got %q\n", e, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestScanActionOutputFile(t *testing.T) {
+	tf, cleanup := setup(t, []string{"unknownhostoutthere"}, true)
+	defer cleanup()
+
+	outFile := filepath.Join(t.TempDir(), "results.csv")
+	err := writeFile(outFile, func(out io.Writer) error {
+		return scanAction(context.Background(), out, scanConfig{hostsFile: tf, sel: selection{ports: []int{22}}, output: outputCSV})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedOut := "host,addresses,port,protocol,state,latency_ms,service,version,error\n" +
+		"unknownhostoutthere,,,,not found,,,,\n"
+	if string(data) != expectedOut {
+		t.Errorf("expected output: %q, got %q\n", expectedOut, string(data))
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		expectOut string
+	}{
+		{"Done", nil, "new"},
+		{"Interrupted", ErrInterrupted, "new"},
+		{"Failed", scan.ErrNotExists, "old"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			outFile := filepath.Join(dir, "results.json")
+			if err := os.WriteFile(outFile, []byte("old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := writeFile(outFile, func(out io.Writer) error {
+				io.WriteString(out, "new")
+				return tc.err
+			})
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected error %v, got %v instead\n", tc.err, err)
+			}
+
+			data, err := os.ReadFile(outFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != tc.expectOut {
+				t.Errorf("Expected %q in the file, got %q instead\n", tc.expectOut, string(data))
+			}
+
+			// the temporary file is gone
+			if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+				t.Errorf("Expected only the output file in %s, got %v\n", dir, entries)
+			}
+		})
+	}
+}
+
+func TestListActionPatterns(t *testing.T) {
+	hosts := []string{
+		"10.0.0.0/30",
+		"10.0.0.2-5",
+		"host1",
+		"web[01-03].internal",
+	}
+
+	tf, cleanup := setup(t, hosts, true)
+	defer cleanup()
+
+	// 10.0.0.2 and 10.0.0.3 are in both the block and the range
+	expectedOut := "10.0.0.0/30 (4 hosts)\n" +
+		"10.0.0.2-5 (4 hosts)\n" +
+		"host1\n" +
+		"web[01-03].internal (3 hosts)\n" +
+		"4 entries, 10 hosts\n"
+
+	var out bytes.Buffer
+	if err := listAction(&out, tf, nil); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if out.String() != expectedOut {
+		t.Errorf("expected output %q, got %q\n", expectedOut, out.String())
+	}
+}
+
+func TestHostInfoActions(t *testing.T) {
+	tf, cleanup := setup(t, nil, false)
+	defer cleanup()
+
+	var out bytes.Buffer
+
+	web := scan.HostInfo{
+		Groups:      []string{"web"},
+		Labels:      map[string]string{"env": "prod"},
+		Description: "front end",
+	}
+	if err := addAction(&out, tf, []string{"web[1-2]"}, web); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if err := addAction(&out, tf, []string{"db1"}, scan.HostInfo{}); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	update := func(i *scan.HostInfo) {
+		i.Labels["tier"] = "1"
+		i.Ports = "8080"
+	}
+	if err := setAction(&out, tf, []string{"web[1-2]"}, update); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	setPorts := func(g *scan.Group) { g.Ports = "80,443" }
+	if err := setGroupAction(&out, tf, "web", setPorts); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if err := listAction(&out, tf, nil); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if err := listGroupsAction(&out, tf); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if err := deleteGroupAction(&out, tf, "web"); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if err := listGroupsAction(&out, tf); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	expectedOut := "Added host: web[1-2]\n" +
+		"Added host: db1\n" +
+		"Updated host: web[1-2]\n" +
+		"Updated group: web\n" +
+		"db1\n" +
+		"web[1-2] (2 hosts) [web] env=prod tier=1 ports=8080 - front end\n" +
+		"2 entries, 3 hosts\n" +
+		"web (1 entries) ports=80,443\n" +
+		"Deleted group: web\n"
+
+	if out.String() != expectedOut {
+		t.Errorf("expected output %q, got %q\n", expectedOut, out.String())
+	}
+
+	if err := setAction(&out, tf, []string{"db2"}, update); !errors.Is(err, scan.ErrNotExists) {
+		t.Errorf("Expected error %q, got %q instead\n", scan.ErrNotExists, err)
+	}
+}
+
+func TestScanActionGroups(t *testing.T) {
+	tf, cleanup := setup(t, nil, false)
+	defer cleanup()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	hl := &scan.HostsList{}
+	for _, h := range []string{"127.0.0.1", "unknownhostoutthere"} {
+		if err := hl.Add(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hl.Update("127.0.0.1", func(i *scan.HostInfo) {
+		i.Groups = []string{"web"}
+		i.Labels = map[string]string{"env": "prod"}
+	})
+	hl.Update("unknownhostoutthere", func(i *scan.HostInfo) {
+		i.Groups = []string{"web"}
+		i.Labels = map[string]string{"env": "staging"}
+	})
+	hl.SetGroup("web", scan.Group{Ports: strconv.Itoa(port)})
+	if err := hl.Save(tf); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		sel       selection
+		expectOut string
+		expectErr error
+	}{
+		{
+			name:      "GroupPorts",
+			sel:       selection{groups: []string{"web"}, labels: map[string]string{"env": "prod"}, ports: []int{22}},
+			expectOut: fmt.Sprintf(`^127\.0\.0\.1:\n\t%d: open \(.+\)\n\n$`, port),
+		},
+		{
+			name:      "Fixed",
+			sel:       selection{labels: map[string]string{"env": "staging"}, ports: []int{22}, fixed: true},
+			expectOut: `^unknownhostoutthere: Host not found\n\n$`,
+		},
+		{
+			name:      "NoMatch",
+			sel:       selection{groups: []string{"db"}, ports: []int{22}},
+			expectErr: scan.ErrNotExists,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := scanAction(context.Background(), &out, scanConfig{hostsFile: tf, sel: tc.sel, output: outputText})
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Fatalf("Expected error %q, got %q instead\n", tc.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			if !regexp.MustCompile(tc.expectOut).MatchString(out.String()) {
+				t.Errorf("expected output matching %q, got %q\n", tc.expectOut, out.String())
+			}
+		})
+	}
+}
+
+func TestHistoryActions(t *testing.T) {
+	tf, cleanup := setup(t, []string{"127.0.0.1"}, true)
+	defer cleanup()
+
+	historyFile := filepath.Join(t.TempDir(), "pScan.db")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	sel := selection{ports: []int{port}}
+
+	var out bytes.Buffer
+	if err := diffAction(&out, historyFile, nil); !errors.Is(err, history.ErrNotFound) {
+		t.Errorf("Expected error %q, got %q instead\n", history.ErrNotFound, err)
+	}
+
+	// the port is open in the first two runs and closed in the last
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			ln.Close()
+		}
+		if err := scanAction(context.Background(), io.Discard, scanConfig{hostsFile: tf, historyFile: historyFile, sel: sel, output: outputText}); err != nil {
+			t.Fatalf("Unexpected error: %q\n", err)
+		}
+	}
+
+	if err := historyAction(&out, historyFile, 2); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	expectedOut := `^ID +STARTED +DURATION +HOSTS +OPEN +COMMAND\n` +
+		`3 +\S+ \S+ +\S+ +1 +0 +.+\n` +
+		`2 +\S+ \S+ +\S+ +1 +1 +.+\n$`
+	if !regexp.MustCompile(expectedOut).MatchString(out.String()) {
+		t.Errorf("expected output matching %q, got %q\n", expectedOut, out.String())
+	}
+
+	out.Reset()
+	if err := diffAction(&out, historyFile, []int64{1, 2}); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if expect := "No changes between runs 1 and 2\n"; out.String() != expect {
+		t.Errorf("expected output %q, got %q\n", expect, out.String())
+	}
+
+	out.Reset()
+	if err := diffAction(&out, historyFile, nil); !errors.Is(err, ErrChanged) {
+		t.Fatalf("Expected error %q, got %q instead\n", ErrChanged, err)
+	}
+
+	expect := fmt.Sprintf("- 127.0.0.1 %d/tcp closed (open -> closed)\n", port)
+	if out.String() != expect {
+		t.Errorf("expected output %q, got %q\n", expect, out.String())
+	}
+
+	out.Reset()
+	if err := showRunAction(&out, historyFile, 3); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	expectedOut = fmt.Sprintf(`^Run 3, .+\n\n127\.0\.0\.1:\n\t%d: closed \(.+\)\n\n$`, port)
+	if !regexp.MustCompile(expectedOut).MatchString(out.String()) {
+		t.Errorf("expected output matching %q, got %q\n", expectedOut, out.String())
+	}
+}
+
+// recorder is a notifier keeping the changes it's sent
+type recorder struct {
+	changes [][]history.Change
+}
+
+func (r *recorder) notify(t time.Time, changes []history.Change) error {
+	r.changes = append(r.changes, changes)
+	return nil
+}
+
+func TestWatcher(t *testing.T) {
+	open := scan.PortState{Port: 22, Proto: scan.ProtoTCP, State: scan.StateOpen}
+	closed := scan.PortState{Port: 22, Proto: scan.ProtoTCP, State: scan.StateClosed}
+	filtered := scan.PortState{Port: 22, Proto: scan.ProtoTCP, State: scan.StateFiltered}
+
+	scans := [][]scan.Results{
+		{{Host: "host1", PortStates: []scan.PortState{open}}},
+		{{Host: "host1", PortStates: []scan.PortState{open}}},
+		nil,
+		{{Host: "host1", PortStates: []scan.PortState{filtered}}},
+		{{Host: "host1", PortStates: []scan.PortState{closed}}},
+		// cut short by the cancellation, not recorded
+		{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var errOut bytes.Buffer
+	rec := &recorder{}
+	calls := 0
+	w := &watcher{
+		interval:  time.Millisecond,
+		notifiers: []notifier{rec},
+		state:     &watchState{},
+		errOut:    &errOut,
+		scan: func(context.Context) ([]scan.Results, error) {
+			calls++
+			if calls == len(scans) {
+				cancel()
+			}
+			if scans[calls-1] == nil {
+				return nil, errors.New("scan failed")
+			}
+			return scans[calls-1], nil
+		},
+	}
+
+	w.run(ctx)
+
+	expect := [][]history.Change{
+		{{Kind: history.PortClosed, Host: "host1", Port: 22, Proto: scan.ProtoTCP, From: "open", To: "filtered"}},
+		{{Kind: history.PortChanged, Host: "host1", Port: 22, Proto: scan.ProtoTCP, From: "filtered", To: "closed"}},
+	}
+	if !reflect.DeepEqual(rec.changes, expect) {
+		t.Errorf("Expected changes %v, got %v instead\n", expect, rec.changes)
+	}
+
+	if errOut.String() != "Error: scan failed\n" {
+		t.Errorf("Expected scan error, got %q instead\n", errOut.String())
+	}
+
+	if n := w.state.scans(); n != 4 {
+		t.Errorf("Expected 4 scans in the state, got %d instead\n", n)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got webhookPayload
+	status := http.StatusNoContent
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	n := webhookNotifier{url: ts.URL, client: ts.Client()}
+	now := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+	changes := []history.Change{
+		{Kind: history.HostAppeared, Host: "host2"},
+		{Kind: history.PortOpened, Host: "host1", Port: 80, Proto: scan.ProtoTCP, From: "filtered", To: "open"},
+	}
+
+	if err := n.notify(now, changes); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	expect := webhookPayload{
+		Time: now,
+		Changes: []changeView{
+			{Host: "host2", Change: "appeared", Message: "host2 appeared"},
+			{
+				Host:     "host1",
+				Change:   "opened",
+				Port:     80,
+				Protocol: scan.ProtoTCP,
+				From:     "filtered",
+				To:       "open",
+				Message:  "host1 80/tcp opened (filtered -> open)",
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected payload %+v, got %+v instead\n", expect, got)
+	}
+
+	status = http.StatusInternalServerError
+	if err := n.notify(now, changes); err == nil {
+		t.Errorf("Expected error for status %d\n", status)
+	}
+}
+
+func TestWatchState(t *testing.T) {
+	s := &watchState{}
+	s.set(time.Now(), []scan.Results{{Host: "host1", NotFound: true}})
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		expectStatus int
+	}{
+		{"Get", http.MethodGet, "/", http.StatusOK},
+		{"Post", http.MethodPost, "/", http.StatusMethodNotAllowed},
+		{"NotFound", http.MethodGet, "/other", http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if w.Code != tc.expectStatus {
+				t.Fatalf("Expected status %d, got %d instead\n", tc.expectStatus, w.Code)
+			}
+
+			if tc.expectStatus != http.StatusOK {
+				return
+			}
+
+			var v stateView
+			if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if v.Scans != 1 || len(v.Hosts) != 1 || v.Hosts[0].Host != "host1" || !v.Hosts[0].NotFound {
+				t.Errorf("Unexpected state %+v\n", v)
+			}
+		})
+	}
+}
+
+func TestWatchAction(t *testing.T) {
+	tf, cleanup := setup(t, []string{"unknownhostoutthere"}, true)
+	defer cleanup()
+
+	logFile := filepath.Join(t.TempDir(), "watch.log")
+
+	// stops after the first scan
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := watchConfig{interval: time.Minute, logFile: logFile, listen: "127.0.0.1:0"}
+	var out, errOut bytes.Buffer
+	if err := watchAction(ctx, &out, &errOut, tf, selection{ports: []int{22}}, scan.Options{}, cfg); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	expectedOut := fmt.Sprintf(`^Serving the latest results on http://127\.0\.0\.1:\d+/\nWatching the hosts in %s every 1m0s\nStopped watching\n$`,
+		regexp.QuoteMeta(tf))
+	if !regexp.MustCompile(expectedOut).MatchString(out.String()) {
+		t.Errorf("expected output matching %q, got %q\n", expectedOut, out.String())
+	}
+
+	if _, err := os.Stat(logFile); err != nil {
+		t.Errorf("Expected the log file to be created: %q\n", err)
+	}
+
+	if errOut.Len() != 0 {
+		t.Errorf("Unexpected errors: %q\n", errOut.String())
+	}
+}
+
+func TestScanActionInterrupted(t *testing.T) {
+	tf, cleanup := setup(t, []string{"localhost", "unknownhostoutthere"}, true)
+	defer cleanup()
+
+	historyFile := filepath.Join(t.TempDir(), "pScan.db")
+	ports, err := scan.ParsePorts("1-65535")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var out, progress bytes.Buffer
+	cfg := scanConfig{
+		hostsFile:   tf,
+		historyFile: historyFile,
+		sel:         selection{ports: ports},
+		output:      outputText,
+		progress:    &progress,
+	}
+
+	err = scanAction(ctx, &out, cfg)
+	if !errors.Is(err, ErrInterrupted) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected error %q, got %q instead\n", ErrInterrupted, err)
+	}
+
+	// localhost is printed with the ports scanned so far, then the host
+	// that wasn't found
+	expectedOut := `^localhost \(.+\):\n(\t\d+: \S+( \(.+\))?\n)+\nunknownhostoutthere: Host not found\n\n$`
+	if !regexp.MustCompile(expectedOut).MatchString(out.String()) {
+		t.Errorf("expected output matching %q, got %q\n", expectedOut, out.String())
+	}
+
+	if strings.Count(out.String(), "\t") == len(ports) {
+		t.Errorf("Expected the scan to be cut short\n")
+	}
+
+	if !strings.Contains(progress.String(), "/65535 ports") {
+		t.Errorf("Expected a progress bar, got %q\n", progress.String())
+	}
+
+	if _, err := os.Stat(historyFile); err == nil {
+		t.Errorf("Expected the stopped scan not to be saved\n")
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	var out bytes.Buffer
+	b := newProgressBar(&out)
+	b.width = 4
+
+	b.update(1, 4)
+	b.update(1, 4)
+	b.clear()
+	b.update(4, 4)
+
+	expect := "\r[=   ] 1/4 ports" +
+		"\r                \r" +
+		"\r[====] 4/4 ports"
+	if out.String() != expect {
+		t.Errorf("Expected %q, got %q instead\n", expect, out.String())
+	}
+
+	// without a writer there is no bar
+	var none *progressBar = newProgressBar(nil)
+	none.update(1, 2)
+	none.clear()
+}
+
+func TestImportAction(t *testing.T) {
+	testCases := []struct {
+		name        string
+		format      string
+		input       string
+		expectErr   error
+		expectHosts []string
+		expectList  string
+	}{
+		{
+			name:        "List",
+			input:       "# lab hosts\nhost2\n\nweb[01-02].internal # web servers\n10.0.0.0/30\n",
+			expectHosts: []string{"10.0.0.0/30", "host1", "host2", "web[01-02].internal"},
+			expectList: "10.0.0.0/30 (4 hosts) [lab]\nhost1\nhost2 [lab]\n" +
+				"web[01-02].internal (2 hosts) [lab]\n4 entries, 8 hosts\n",
+		},
+		{
+			name:        "Hosts",
+			input:       "127.0.0.1\tlocalhost\n10.0.0.5 db1 db1.internal # primary\n",
+			expectHosts: []string{"10.0.0.5", "127.0.0.1", "host1"},
+			expectList: "10.0.0.5 [lab] - db1 db1.internal\n127.0.0.1 [lab] - localhost\n" +
+				"host1\n3 entries, 3 hosts\n",
+		},
+		{
+			name: "JSON",
+			input: `[{"host": "host2", "address": "10.0.0.2", "not_found": false, "ports": []},
+{"host": "host2", "address": "10.0.0.3", "not_found": false, "ports": []},
+{"host": "host3", "not_found": true, "ports": []}]`,
+			expectHosts: []string{"host1", "host2"},
+		},
+		{
+			name:        "Existing",
+			input:       "host1\nhost2\n",
+			expectErr:   scan.ErrExists,
+			expectHosts: []string{"host1", "host2"},
+		},
+		{
+			name:        "InvalidHost",
+			input:       "host2\nhost 3\n",
+			format:      formatList,
+			expectErr:   ErrInvalidFormat,
+			expectHosts: []string{"host1"},
+		},
+		{
+			name:        "InvalidName",
+			input:       "host2\n-host3\n",
+			expectErr:   scan.ErrInvalidHost,
+			expectHosts: []string{"host1"},
+		},
+		{
+			name:        "InvalidHostsLine",
+			format:      formatHosts,
+			input:       "10.0.0.5 db1\ndb2\n",
+			expectErr:   ErrInvalidFormat,
+			expectHosts: []string{"host1"},
+		},
+		{
+			name:        "InvalidFormat",
+			format:      "yaml",
+			input:       "host2\n",
+			expectErr:   ErrInvalidFormat,
+			expectHosts: []string{"host1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tf, cleanup := setup(t, []string{"host1"}, true)
+			defer cleanup()
+
+			var out bytes.Buffer
+			err := importAction(&out, tf, strings.NewReader(tc.input), tc.format, scan.HostInfo{Groups: []string{"lab"}})
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("Expected error %q, got %q instead\n", tc.expectErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			hl := &scan.HostsList{}
+			if err := hl.Load(tf); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(hl.Hosts, tc.expectHosts) {
+				t.Errorf("Expected hosts %v, got %v instead\n", tc.expectHosts, hl.Hosts)
+			}
+
+			if tc.expectList == "" {
+				return
+			}
+
+			out.Reset()
+			if err := listAction(&out, tf, nil); err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			if out.String() != tc.expectList {
+				t.Errorf("Expected list %q, got %q instead\n", tc.expectList, out.String())
+			}
+		})
+	}
+}
+
+func TestExportAction(t *testing.T) {
+	tf, cleanup := setup(t, []string{"host1", "10.0.0.0/31", "127.0.0.1"}, true)
+	defer cleanup()
+
+	hl := &scan.HostsList{}
+	if err := hl.Load(tf); err != nil {
+		t.Fatal(err)
+	}
+	hl.Update("10.0.0.0/31", func(i *scan.HostInfo) { i.Description = "lab" })
+	hl.Update("127.0.0.1", func(i *scan.HostInfo) { i.Description = "localhost lo" })
+	if err := hl.Save(tf); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		format       string
+		expectOut    string
+		expectErrOut string
+	}{
+		{formatList, "10.0.0.0/31\n127.0.0.1\nhost1\n", ""},
+		{formatHosts, "10.0.0.0\tlab\n10.0.0.1\tlab\n127.0.0.1\tlocalhost\tlo\n", "Skipped 1 hosts without an address or names\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if err := exportAction(&out, &errOut, tf, tc.format); err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			if out.String() != tc.expectOut {
+				t.Errorf("Expected output %q, got %q instead\n", tc.expectOut, out.String())
+			}
+
+			if errOut.String() != tc.expectErrOut {
+				t.Errorf("Expected error output %q, got %q instead\n", tc.expectErrOut, errOut.String())
+			}
+		})
+	}
+
+	// what export writes, import reads back
+	for _, format := range []string{formatList, formatHosts, formatJSON} {
+		t.Run("RoundTrip"+format, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if err := exportAction(&out, &errOut, tf, format); err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			copyFile, cleanup := setup(t, nil, false)
+			defer cleanup()
+
+			if err := importAction(io.Discard, copyFile, &out, "", scan.HostInfo{}); err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			hl := &scan.HostsList{}
+			if err := hl.Load(copyFile); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(hl.Hosts) == 0 {
+				t.Errorf("Expected hosts imported from %s export\n", format)
+			}
+		})
+	}
+}
+
+func TestInitConfig(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "pScan.yaml")
+	config := `hosts-file: lab.hosts
+profiles:
+  quick:
+    ports: 22,80
+    timeout: 300ms
+    workers: 200
+    output: json
+  bad:
+    port: 22
+`
+	if err := os.WriteFile(cfgFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		args      []string
+		env       map[string]string
+		expect    map[string]string
+		expectErr error
+	}{
+		{
+			name:   "NoProfile",
+			expect: map[string]string{"hosts-file": "lab.hosts", "ports": "22,80,443", "output": outputText},
+		},
+		{
+			name:   "Profile",
+			args:   []string{"--profile", "quick"},
+			expect: map[string]string{"ports": "22,80", "timeout": "300ms", "workers": "200", "output": outputJSON},
+		},
+		{
+			name:   "FlagsWin",
+			args:   []string{"-P", "quick", "--timeout", "1s", "--top-ports", "5"},
+			expect: map[string]string{"ports": "22,80,443", "top-ports": "5", "timeout": "1s", "workers": "200"},
+		},
+		{
+			name:   "Env",
+			env:    map[string]string{"PSCAN_PROFILE": "quick", "PSCAN_WORKERS": "10", "PSCAN_HOSTS_FILE": "env.hosts"},
+			expect: map[string]string{"ports": "22,80", "workers": "10", "hosts-file": "env.hosts"},
+		},
+		{
+			name:      "InvalidEnv",
+			env:       map[string]string{"PSCAN_TIMEOUT": "soon"},
+			expectErr: ErrInvalidConfig,
+		},
+		{
+			name:      "ProfileNotFound",
+			args:      []string{"--profile", "full"},
+			expectErr: ErrProfileNotFound,
+		},
+		{
+			name:      "UnknownSetting",
+			args:      []string{"--profile", "bad"},
+			expectErr: ErrInvalidConfig,
+		},
+		{
+			name:      "MissingConfig",
+			args:      []string{"--config", filepath.Join(t.TempDir(), "missing.yaml")},
+			expectErr: ErrInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			cmd := &cobra.Command{Use: "scan"}
+			cmd.Flags().String("config", "", "")
+			cmd.Flags().String("hosts-file", "pScan.hosts", "")
+			cmd.Flags().String("output", outputText, "")
+			addScanFlags(cmd)
+
+			if err := cmd.ParseFlags(append([]string{"--config", cfgFile}, tc.args...)); err != nil {
+				t.Fatal(err)
+			}
+
+			err := initConfig(cmd)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Fatalf("Expected error %q, got %q instead\n", tc.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			for k, v := range tc.expect {
+				if got := cmd.Flags().Lookup(k).Value.String(); got != v {
+					t.Errorf("Expected %s %q, got %q instead\n", k, v, got)
+				}
+			}
+		})
+	}
+}
+
+// ports from a profile don't replace the ports of each host, as ports
+// on the command line do
+func TestScanSettingsConfig(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "pScan.yaml")
+	config := `profiles:
+  quick:
+    ports: 22,80
+  top:
+    top-ports: 3
+  udp:
+    udp: true
+    ports: 22,80
+`
+	if err := os.WriteFile(cfgFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name        string
+		args        []string
+		expectPorts []int
+		expectFixed bool
+	}{
+		{"Profile", []string{"-P", "quick"}, []int{22, 80}, false},
+		{"TopPorts", []string{"-P", "top"}, []int{80, 23, 443}, false},
+		{"CommandLine", []string{"-P", "quick", "--ports", "443"}, []int{443}, true},
+		{"UDP", []string{"-P", "udp"}, []int{53, 123, 161}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "scan"}
+			cmd.Flags().String("config", "", "")
+			addScanFlags(cmd)
+
+			if err := cmd.ParseFlags(append([]string{"--config", cfgFile}, tc.args...)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := initConfig(cmd); err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			sel, _, err := scanSettings(cmd)
+			if err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			if !reflect.DeepEqual(sel.ports, tc.expectPorts) || sel.fixed != tc.expectFixed {
+				t.Errorf("Expected ports %v fixed %t, got %v fixed %t instead\n",
+					tc.expectPorts, tc.expectFixed, sel.ports, sel.fixed)
+			}
+		})
+	}
+}
+
+// the ports of a profile aren't recorded for the hosts discover adds
+func TestInitConfigHostInfo(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "pScan.yaml")
+	config := `profiles:
+  quick:
+    ports: 22,80
+    timeout: 300ms
+`
+	if err := os.WriteFile(cfgFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{Use: "discover"}
+	cmd.Flags().String("config", "", "")
+	cmd.Flags().StringP("profile", "P", "", "")
+	cmd.Flags().DurationP("timeout", "t", scan.DefaultTimeout, "")
+	addHostInfoFlags(cmd)
+
+	if err := cmd.ParseFlags([]string{"--config", cfgFile, "-P", "quick"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := initConfig(cmd); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if got := cmd.Flags().Lookup("timeout").Value.String(); got != "300ms" {
+		t.Errorf("Expected timeout 300ms from the profile, got %q instead\n", got)
+	}
+
+	info, err := hostInfo(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Ports != "" {
+		t.Errorf("Expected no host ports, got %q instead\n", info.Ports)
+	}
+}
+
+// commands without --profile ignore the default profile, whatever
+// settings it has
+func TestInitConfigDefaultProfile(t *testing.T) {
+	tf, cleanup := setup(t, []string{"localhost"}, true)
+	defer cleanup()
+
+	cfgFile := filepath.Join(t.TempDir(), "pScan.yaml")
+	config := fmt.Sprintf(`hosts-file: %s
+profile: quick
+profiles:
+  quick:
+    ports: 22,80
+`, tf)
+	if err := os.WriteFile(cfgFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"ConfigFile", nil},
+		{"Env", map[string]string{"PSCAN_PROFILE": "quick"}},
+	}
+
+	// rootCmd keeps the flags of the last run
+	reset := func() {
+		for _, name := range []string{"config", "hosts-file"} {
+			f := rootCmd.PersistentFlags().Lookup(name)
+			f.Value.Set(f.DefValue)
+			f.Changed = false
+		}
+	}
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		reset()
+	})
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			reset()
+			rootCmd.SetArgs([]string{"--config", cfgFile, "hosts", "list"})
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			if got := rootCmd.PersistentFlags().Lookup("hosts-file").Value.String(); got != tf {
+				t.Errorf("Expected hosts file %q from the config, got %q instead\n", tf, got)
+			}
+		})
+	}
+}
+
+func TestDiscoverAction(t *testing.T) {
+	// a closed port answers the ping as well as an open one
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	opts := scan.Options{PingPorts: []int{closed}, Timeout: 500 * time.Millisecond}
+	up := func(host string) string {
+		return fmt.Sprintf(`%s: up \(tcp/%d, [0-9.]+[µm]?s\)\n`, regexp.QuoteMeta(host), closed)
+	}
+
+	testCases := []struct {
+		name        string
+		entries     []string
+		add         bool
+		canceled    bool
+		expectOut   string
+		expectErr   error
+		expectHosts []string
+	}{
+		{
+			name:        "Up",
+			entries:     []string{"127.0.0.1-2", "127.0.0.2", "unknownhostoutthere"},
+			expectOut:   "^" + up("127.0.0.1") + up("127.0.0.2") + `2 of 3 hosts up\n$`,
+			expectHosts: []string{"127.0.0.1"},
+		},
+		{
+			name:        "Add",
+			entries:     []string{"127.0.0.1-2", "unknownhostoutthere"},
+			add:         true,
+			expectOut:   "^" + up("127.0.0.1") + up("127.0.0.2") + `2 of 3 hosts up\nAdded 1 hosts\n$`,
+			expectHosts: []string{"127.0.0.1", "127.0.0.2"},
+		},
+		{
+			name:      "Invalid",
+			entries:   []string{"bad host!"},
+			expectErr: scan.ErrInvalidHost,
+		},
+		{
+			name:        "Interrupted",
+			entries:     []string{"127.0.0.1"},
+			add:         true,
+			canceled:    true,
+			expectOut:   `^0 of 1 hosts up\n$`,
+			expectErr:   ErrInterrupted,
+			expectHosts: []string{"127.0.0.1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tf, cleanup := setup(t, []string{"127.0.0.1"}, true)
+			defer cleanup()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.canceled {
+				cancel()
+			}
+
+			cfg := discoverConfig{
+				hostsFile: tf,
+				opts:      opts,
+				add:       tc.add,
+				info:      scan.HostInfo{Groups: []string{"lab"}},
+			}
+
+			var out bytes.Buffer
+			err := discoverAction(ctx, &out, tc.entries, cfg)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Fatalf("Expected error %q, got %v instead\n", tc.expectErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			if tc.expectOut != "" && !regexp.MustCompile(tc.expectOut).MatchString(out.String()) {
+				t.Errorf("Expected output matching %q, got %q instead\n", tc.expectOut, out.String())
+			}
+
+			if tc.expectHosts == nil {
+				return
+			}
+
+			hl := &scan.HostsList{}
+			if err := hl.Load(tf); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(hl.Hosts, tc.expectHosts) {
+				t.Errorf("Expected hosts %v, got %v instead\n", tc.expectHosts, hl.Hosts)
+			}
+
+			if tc.add && len(hl.Hosts) > 1 && !hl.Info["127.0.0.2"].InGroup("lab") {
+				t.Errorf("Expected 127.0.0.2 added to group lab, got %+v\n", hl.Info["127.0.0.2"])
+			}
+		})
+	}
+}
+
+func TestDocsActionConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := docsAction(io.Discard, dir); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	page, err := os.ReadFile(filepath.Join(dir, "pScan.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := strings.Index(string(page), "### Configuration")
+	if config == -1 || config > strings.Index(string(page), "### Options") {
+		t.Errorf("Expected the config docs before the options, got %q\n", page)
+	}
+}
@@ -17,6 +17,13 @@ var addCmd = &cobra.Command{
 	Use:          "add <host1>...<hostn>",
 	Aliases:      []string{"a"},
 	Short:        "Add new host(s) to list",
+	Long: `Add new hosts to the list.
+
+Hosts can be host names, addresses, CIDR blocks like 10.0.0.0/24,
+address ranges like 192.168.1.10-50 and name patterns like
+web[01-10].internal. Use --group, --label, --description and --ports
+to record the groups, labels, description and ports to scan of the new
+hosts.`,
 	Args:         cobra.MinimumNArgs(1),
 	SilenceUsage: true,
 
@@ -26,13 +33,20 @@ var addCmd = &cobra.Command{
 			return err
 		}
 
-		return addAction(os.Stdout, hostsFile, args)
+		info, err := hostInfo(cmd)
+		if err != nil {
+			return err
+		}
+
+		return addAction(os.Stdout, hostsFile, args, info)
 	},
 }
 
 func init() {
 	hostsCmd.AddCommand(addCmd)
 
+	addHostInfoFlags(addCmd)
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -44,7 +58,7 @@ func init() {
 	// addCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func addAction(out io.Writer, hostsFile string, args []string) error {
+func addAction(out io.Writer, hostsFile string, args []string, info scan.HostInfo) error {
 	hl := &scan.HostsList{}
 	if err := hl.Load(hostsFile); err != nil {
 		return err
@@ -54,6 +68,7 @@ func addAction(out io.Writer, hostsFile string, args []string) error {
 		if err := hl.Add(h); err != nil {
 			return err
 		}
+		hl.Update(h, func(i *scan.HostInfo) { *i = info })
 		fmt.Fprintln(out, "Added host:", h)
 	}
 	
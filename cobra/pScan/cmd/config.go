@@ -0,0 +1,182 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrInvalidConfig   = errors.New("invalid config")
+)
+
+// globalKeys are the settings of every command the config file can set
+var globalKeys = []string{"hosts-file", "history-file"}
+
+// profileKeys are the settings of scan, watch and discover a profile
+// can set
+var profileKeys = []string{
+	"ports", "exclude-ports", "top-ports",
+	"workers", "rate", "timeout",
+	"udp", "service-detect", "tls-inspect", "tls-expiry-days",
+	"skip-down", "ping-ports", "icmp",
+	"output", "interval",
+}
+
+// fromConfig annotates the flags initConfig sets, which cobra marks as
+// changed like those given on the command line
+const fromConfig = "pscan_from_config"
+
+// flagGiven reports whether the flag name of cmd was given on the
+// command line, not set from the environment or the config file
+func flagGiven(cmd *cobra.Command, name string) bool {
+	f := cmd.Flags().Lookup(name)
+	if f == nil || !f.Changed {
+		return false
+	}
+
+	_, ok := f.Annotations[fromConfig]
+	return !ok
+}
+
+func defaultConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".pScan.yaml"
+	}
+
+	return filepath.Join(home, ".pScan.yaml")
+}
+
+// initConfig sets the flags of cmd not given on the command line from
+// PSCAN_* environment variables, or the config file. Commands with a
+// --profile flag get the settings of the profile too
+func initConfig(cmd *cobra.Command) error {
+	cfgFile, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return err
+	}
+
+	if cfgFile == "" {
+		cfgFile = defaultConfigFile()
+	}
+
+	profile := ""
+	keys := globalKeys
+	withProfile := cmd.Flags().Lookup("profile") != nil
+	if withProfile {
+		if profile, err = cmd.Flags().GetString("profile"); err != nil {
+			return err
+		}
+		keys = append(append([]string{}, globalKeys...), profileKeys...)
+	}
+
+	v, err := loadConfig(cfgFile, cmd.Flags().Changed("config"), withProfile, profile, keys)
+	if err != nil {
+		return err
+	}
+
+	// a ports setting given on the command line beats any other
+	userPorts := flagGiven(cmd, "ports") || flagGiven(cmd, "top-ports")
+
+	for _, k := range keys {
+		f := cmd.Flags().Lookup(k)
+		if f == nil || f.Changed || !v.IsSet(k) {
+			continue
+		}
+
+		if _, ok := f.Annotations[hostInfoFlag]; ok {
+			continue
+		}
+
+		if userPorts && (k == "ports" || k == "top-ports") {
+			continue
+		}
+
+		if err := cmd.Flags().Set(k, v.GetString(k)); err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidConfig, k, err)
+		}
+		if err := cmd.Flags().SetAnnotation(k, fromConfig, []string{"true"}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadConfig reads cfgFile, which may be missing unless required, with
+// PSCAN_* environment variables on top. With withProfile, the settings of
+// profile go on top of the ones at the top level and, without profile,
+// the one named by PSCAN_PROFILE or the profile setting is used, if any
+func loadConfig(cfgFile string, required, withProfile bool, profile string, keys []string) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetEnvPrefix("PSCAN")
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+
+	v.SetConfigFile(cfgFile)
+	if filepath.Ext(cfgFile) == "" {
+		v.SetConfigType("yaml")
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		if required || !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+		}
+	}
+
+	if !withProfile {
+		return v, nil
+	}
+
+	if profile == "" {
+		profile = v.GetString("profile")
+	}
+	if profile == "" {
+		return v, nil
+	}
+
+	profiles := v.GetStringMap("profiles")
+	p, ok := profiles[profile].(map[string]any)
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for n := range profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("%w: %q, available profiles: %v", ErrProfileNotFound, profile, names)
+	}
+
+	for k := range p {
+		if !knownKey(keys, k) {
+			return nil, fmt.Errorf("%w: unknown setting %q in profile %q", ErrInvalidConfig, k, profile)
+		}
+	}
+
+	if err := v.MergeConfigMap(p); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func knownKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bedminer1/cobra/pScan/history"
+	"github.com/spf13/cobra"
+)
+
+// ErrChanged is returned by diff when the runs differ
+var ErrChanged = errors.New("scan results changed")
+
+// exit status when the runs differ, to tell it from errors
+const exitChanged = 2
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:          "diff [run1 run2]",
+	Short:        "Compare the results of two scans",
+	SilenceUsage: true,
+	// changes aren't errors, Execute prints the errors
+	SilenceErrors: true,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf("accepts 0 or 2 run IDs, received %d", len(args))
+		}
+		return nil
+	},
+	Long: `Compare the results of two scans in the history, by default the
+last two.
+
+Lists the hosts that appeared or disappeared, and the ports scanned in
+both runs that opened or closed. pScan exits with status 2 when there
+are changes, so scheduled scans can alert on them, and 1 on errors.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		historyFile, err := cmd.Flags().GetString("history-file")
+		if err != nil {
+			return err
+		}
+
+		var ids []int64
+		for _, a := range args {
+			id, err := parseRunID(a)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+
+		return diffAction(os.Stdout, historyFile, ids)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+// diffAction compares the runs in ids, or the last two runs if ids is
+// empty, and returns ErrChanged if they differ
+func diffAction(out io.Writer, historyFile string, ids []int64) error {
+	store, err := history.Open(historyFile)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if len(ids) == 0 {
+		runs, err := store.Runs(2)
+		if err != nil {
+			return err
+		}
+
+		if len(runs) < 2 {
+			return fmt.Errorf("%w: need two scans to compare, history has %d", history.ErrNotFound, len(runs))
+		}
+
+		ids = []int64{runs[1].ID, runs[0].ID}
+	}
+
+	from, err := store.Get(ids[0])
+	if err != nil {
+		return err
+	}
+
+	to, err := store.Get(ids[1])
+	if err != nil {
+		return err
+	}
+
+	changes := history.Compare(from.Results, to.Results)
+	if len(changes) == 0 {
+		_, err := fmt.Fprintf(out, "No changes between runs %d and %d\n", from.ID, to.ID)
+		return err
+	}
+
+	for _, c := range changes {
+		sign := "+"
+		if c.Kind == history.HostDisappeared || c.Kind == history.PortClosed {
+			sign = "-"
+		}
+		fmt.Fprintf(out, "%s %s\n", sign, c)
+	}
+
+	return fmt.Errorf("%w: %d changes between runs %d and %d", ErrChanged, len(changes), from.ID, to.ID)
+}
@@ -0,0 +1,198 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+	"github.com/spf13/cobra"
+)
+
+// discoverCmd represents the discover command
+var discoverCmd = &cobra.Command{
+	Use:          "discover <host|cidr>...",
+	Short:        "Find the hosts that are up",
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+	Long: `Ping the hosts given, like 10.0.0.0/24, 192.168.1.10-50 or host
+names, and list those that are up.
+
+pScan pings a host by connecting to a few common ports, set with
+--ping-ports: the host is up if any of them accepts or refuses the
+connection. With --icmp, an ICMP echo is sent to IPv4 addresses too,
+which needs root privileges.
+
+With --add, the hosts that are up are added to the hosts list, with
+the --group, --label, --description and --ports given. These flags are
+never set from a profile or the environment. Hosts already in the list
+are left as they are.
+
+Use scan --skip-down to ping the hosts in the list before scanning
+them, and skip the ports of those that are down.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile, err := cmd.Flags().GetString("hosts-file")
+		if err != nil {
+			return err
+		}
+
+		workers, err := cmd.Flags().GetInt("workers")
+		if err != nil {
+			return err
+		}
+
+		rate, err := cmd.Flags().GetInt("rate")
+		if err != nil {
+			return err
+		}
+
+		if err := scan.ValidateRate(rate); err != nil {
+			return err
+		}
+
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			return err
+		}
+
+		pingPorts, icmp, err := pingSettings(cmd)
+		if err != nil {
+			return err
+		}
+
+		cfg := discoverConfig{
+			hostsFile: hostsFile,
+			opts: scan.Options{
+				Workers:   workers,
+				Rate:      rate,
+				Timeout:   timeout,
+				PingPorts: pingPorts,
+				ICMP:      icmp,
+			},
+		}
+
+		if cfg.add, err = cmd.Flags().GetBool("add"); err != nil {
+			return err
+		}
+
+		if cfg.info, err = hostInfo(cmd); err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		return discoverAction(ctx, os.Stdout, args, cfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(discoverCmd)
+
+	discoverCmd.Flags().StringP("profile", "P", "", "scan profile from the config file")
+	discoverCmd.Flags().IntP("workers", "w", scan.DefaultWorkers, "number of hosts pinged at the same time")
+	discoverCmd.Flags().Int("rate", 0, "maximum hosts pinged per second, 0 for no limit")
+	discoverCmd.Flags().DurationP("timeout", "t", scan.DefaultTimeout, "timeout for each ping")
+	addPingFlags(discoverCmd)
+	discoverCmd.Flags().Bool("add", false, "add the hosts that are up to the hosts list")
+	addHostInfoFlags(discoverCmd)
+}
+
+// discoverConfig is how discoverAction pings the hosts and, with add,
+// where it adds those that are up, with info
+type discoverConfig struct {
+	hostsFile string
+	opts      scan.Options
+	add       bool
+	info      scan.HostInfo
+}
+
+// discoverAction pings the hosts in entries and lists those that are
+// up. When ctx is done before the end, the hosts up so far are listed
+// and added, and ErrInterrupted is returned
+func discoverAction(ctx context.Context, out io.Writer, entries []string, cfg discoverConfig) error {
+	// validates the entries and leaves out the hosts given twice
+	hl := &scan.HostsList{}
+	for _, e := range entries {
+		if err := hl.Add(e); err != nil && !errors.Is(err, scan.ErrExists) {
+			return err
+		}
+	}
+	hosts := hl.Expand()
+
+	status, err := scan.Discover(ctx, hosts, cfg.opts)
+	interrupted := errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+	if err != nil && !interrupted {
+		return err
+	}
+
+	up := []string{}
+	for _, s := range status {
+		if !s.Up {
+			continue
+		}
+
+		up = append(up, s.Host)
+		if _, err := fmt.Fprintf(out, "%s: up (%s, %s)\n", s.Host, s.Reason, s.Latency.Round(time.Microsecond)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(out, "%d of %d hosts up\n", len(up), len(hosts)); err != nil {
+		return err
+	}
+
+	if cfg.add && len(up) > 0 {
+		if err := addUp(out, cfg.hostsFile, up, cfg.info); err != nil {
+			return err
+		}
+	}
+
+	if interrupted {
+		return fmt.Errorf("%w: %w", ErrInterrupted, err)
+	}
+
+	return nil
+}
+
+// addUp adds the hosts in up to the list in hostsFile with info, unless
+// they are in it already
+func addUp(out io.Writer, hostsFile string, up []string, info scan.HostInfo) error {
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	added := 0
+	for _, h := range up {
+		if err := hl.Add(h); err != nil {
+			if errors.Is(err, scan.ErrExists) {
+				continue
+			}
+			return err
+		}
+
+		hl.Update(h, func(i *scan.HostInfo) { *i = info })
+		added++
+	}
+
+	if added == 0 {
+		return nil
+	}
+
+	if err := hl.Save(hostsFile); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "Added %d hosts\n", added)
+	return err
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
@@ -39,11 +41,56 @@ func init() {
 	docsCmd.Flags().StringP("dir", "d", "", "Destination directory for docs")
 }
 
+// configDocs documents the config file in the page of the root command
+const configDocs = `### Configuration
+
+Settings are read from command line flags, PSCAN_* environment
+variables, like PSCAN_HOSTS_FILE or PSCAN_TIMEOUT, and the config file,
+in that order. The config file is YAML: hosts-file and history-file
+set the files of every command, and profiles holds named sets of scan
+settings, used by scan, watch and discover with --profile or
+PSCAN_PROFILE:
+
+    hosts-file: lab.hosts
+    profile: quick
+    profiles:
+      quick:
+        ports: 22,80,443
+        timeout: 300ms
+        workers: 200
+        output: json
+
+A profile can set ports, exclude-ports, top-ports, workers, rate,
+timeout, udp, service-detect, tls-inspect, tls-expiry-days, skip-down,
+ping-ports, icmp, output and interval. The profile setting picks the
+profile used when none is given.
+
+Ports set in a profile or the environment are defaults: hosts with
+ports of their own in the hosts list are still scanned on those.
+
+`
+
 func docsAction(out io.Writer, dir string) error {
 	if err := doc.GenMarkdownTree(rootCmd, dir); err != nil {
 		return err
 	}
 
+	if err := addConfigDocs(filepath.Join(dir, rootCmd.Name()+".md")); err != nil {
+		return err
+	}
+
 	_, err := fmt.Fprintf(out, "Documentation successfully created in %s\n", dir)
 	return err
 }
+
+// addConfigDocs adds configDocs to the root command page in file,
+// before its options
+func addConfigDocs(file string) error {
+	page, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	withConfig := strings.Replace(string(page), "### Options", configDocs+"### Options", 1)
+	return os.WriteFile(file, []byte(withConfig), 0644)
+}
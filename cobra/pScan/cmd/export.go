@@ -0,0 +1,129 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:          "export",
+	Short:        "Write the hosts list in a format import reads",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	Long: `Write the hosts list to STDOUT, or to the file given with --output-file.
+
+With --format list, the default, the entries are written one per line.
+With --format hosts, the list is written in the format of /etc/hosts:
+CIDR blocks and ranges are expanded and each address is followed by
+the names in its description. Host names and addresses without a
+description are left out, and their number is reported. With --format
+json, the entries are written like the JSON results of a scan, without
+ports.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile, err := cmd.Flags().GetString("hosts-file")
+		if err != nil {
+			return err
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		if err := validateFormat(format); err != nil {
+			return err
+		}
+
+		outputFile, err := cmd.Flags().GetString("output-file")
+		if err != nil {
+			return err
+		}
+
+		if outputFile == "" {
+			return exportAction(os.Stdout, os.Stderr, hostsFile, format)
+		}
+
+		return writeFile(outputFile, func(out io.Writer) error {
+			return exportAction(out, os.Stderr, hostsFile, format)
+		})
+	},
+}
+
+func init() {
+	hostsCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().String("format", formatList, "output format: list, hosts or json")
+	exportCmd.Flags().String("output-file", "", "write the hosts to this file instead of stdout")
+}
+
+// exportAction writes the hosts list to out in format, and what was
+// left out to errOut
+func exportAction(out, errOut io.Writer, hostsFile, format string) error {
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	switch format {
+	case formatList:
+		for _, h := range hl.Hosts {
+			if _, err := fmt.Fprintln(out, h); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatHosts:
+		return exportHosts(out, errOut, hl)
+	case formatJSON:
+		views := make([]hostView, 0, len(hl.Hosts))
+		for _, h := range hl.Hosts {
+			views = append(views, hostView{Host: h, Ports: []portView{}})
+		}
+
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(views)
+	}
+
+	return validateFormat(format)
+}
+
+// exportHosts writes the addresses in hl with the names in their
+// description, one per line
+func exportHosts(out, errOut io.Writer, hl *scan.HostsList) error {
+	skipped := 0
+	for _, entry := range hl.Hosts {
+		names := lineFields(hl.Info[entry].Description)
+		for _, h := range scan.EntryHosts(entry) {
+			if _, err := netip.ParseAddr(h); err != nil || len(names) == 0 {
+				skipped++
+				continue
+			}
+
+			line := h
+			for _, n := range names {
+				line += "\t" + n
+			}
+			if _, err := fmt.Fprintln(out, line); err != nil {
+				return err
+			}
+		}
+	}
+
+	if skipped > 0 {
+		fmt.Fprintf(errOut, "Skipped %d hosts without an address or names\n", skipped)
+	}
+
+	return nil
+}
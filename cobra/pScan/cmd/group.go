@@ -0,0 +1,151 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+	"github.com/spf13/cobra"
+)
+
+// groupCmd represents the group command
+var groupCmd = &cobra.Command{
+	Use:          "group [name]",
+	Short:        "List groups, or set the description and ports of a group",
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	Long: `List the groups in the hosts list, or set the description and
+default ports of a group. Only the given flags change.
+
+Hosts without ports of their own are scanned on the ports of the first
+of their groups that has them. Use --delete to remove a group, which
+also takes its hosts out of it.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile, err := cmd.Flags().GetString("hosts-file")
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return listGroupsAction(os.Stdout, hostsFile)
+		}
+
+		del, err := cmd.Flags().GetBool("delete")
+		if err != nil {
+			return err
+		}
+
+		if del {
+			return deleteGroupAction(os.Stdout, hostsFile, args[0])
+		}
+
+		description, err := cmd.Flags().GetString("description")
+		if err != nil {
+			return err
+		}
+
+		ports, err := cmd.Flags().GetString("ports")
+		if err != nil {
+			return err
+		}
+
+		if ports != "" {
+			if _, err := scan.ParsePorts(ports); err != nil {
+				return err
+			}
+		}
+
+		changed := cmd.Flags().Changed
+		return setGroupAction(os.Stdout, hostsFile, args[0], func(g *scan.Group) {
+			if changed("description") {
+				g.Description = description
+			}
+			if changed("ports") {
+				g.Ports = ports
+			}
+		})
+	},
+}
+
+func init() {
+	hostsCmd.AddCommand(groupCmd)
+
+	groupCmd.Flags().StringP("description", "d", "", "description of the group")
+	groupCmd.Flags().StringP("ports", "p", "", "ports to scan on the hosts of the group")
+	groupCmd.Flags().Bool("delete", false, "delete the group")
+}
+
+func listGroupsAction(out io.Writer, hostsFile string) error {
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	// groups can have hosts without being defined
+	members := map[string]int{}
+	for name := range hl.Groups {
+		members[name] = 0
+	}
+	for _, info := range hl.Info {
+		for _, g := range info.Groups {
+			members[g]++
+		}
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		g := hl.Groups[name]
+		line := fmt.Sprintf("%s (%d entries)", name, members[name])
+		if g.Ports != "" {
+			line += " ports=" + g.Ports
+		}
+		if g.Description != "" {
+			line += " - " + g.Description
+		}
+
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setGroupAction(out io.Writer, hostsFile, name string, update func(*scan.Group)) error {
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	g := hl.Groups[name]
+	update(&g)
+	hl.SetGroup(name, g)
+	fmt.Fprintln(out, "Updated group:", name)
+
+	return hl.Save(hostsFile)
+}
+
+func deleteGroupAction(out io.Writer, hostsFile, name string) error {
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	if err := hl.RemoveGroup(name); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Deleted group:", name)
+
+	return hl.Save(hostsFile)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bedminer1/cobra/pScan/history"
+	"github.com/bedminer1/cobra/pScan/scan"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:          "history [run]",
+	Short:        "List past scans, or show the results of one",
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	Long: `List the scans saved in the history file, newest first, with the
+number of hosts scanned and open ports found.
+
+Given a run ID, show the results of that scan instead.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		historyFile, err := cmd.Flags().GetString("history-file")
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			id, err := parseRunID(args[0])
+			if err != nil {
+				return err
+			}
+
+			return showRunAction(os.Stdout, historyFile, id)
+		}
+
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return err
+		}
+
+		return historyAction(os.Stdout, historyFile, limit)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().IntP("limit", "n", 20, "number of runs to list, 0 for all")
+}
+
+func parseRunID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("%w: %q is not a run ID", history.ErrNotFound, arg)
+	}
+
+	return id, nil
+}
+
+// saveRun saves a scan in the history
+func saveRun(historyFile string, info scanInfo, results []scan.Results) error {
+	store, err := history.Open(historyFile)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	_, err = store.Save(history.Run{
+		Start:   info.Start,
+		End:     info.End,
+		Args:    strings.Join(info.Args, " "),
+		Results: results,
+	})
+
+	return err
+}
+
+func historyAction(out io.Writer, historyFile string, limit int) error {
+	store, err := history.Open(historyFile)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	runs, err := store.Runs(limit)
+	if err != nil {
+		return err
+	}
+
+	if len(runs) == 0 {
+		_, err := fmt.Fprintln(out, "No scans in the history")
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 3, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tDURATION\tHOSTS\tOPEN\tCOMMAND")
+	for _, r := range runs {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%s\n",
+			r.ID,
+			r.Start.Local().Format(time.DateTime),
+			r.End.Sub(r.Start).Round(time.Millisecond),
+			r.Hosts,
+			r.Open,
+			r.Args,
+		)
+	}
+
+	return w.Flush()
+}
+
+func showRunAction(out io.Writer, historyFile string, id int64) error {
+	store, err := history.Open(historyFile)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	r, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Run %d, %s\n\n", r.ID, r.Start.Local().Format(time.DateTime))
+	return printResults(out, r.Results)
+}
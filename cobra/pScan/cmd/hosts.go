@@ -13,7 +13,13 @@ var hostsCmd = &cobra.Command{
 	Short: "Manage the hosts list",
 	Long: `Add hosts with the add command
 Delete hosts with the delete command
-List hosts with the list command`,
+List hosts with the list command
+Change the groups, labels, description or ports of hosts with the set command
+Manage groups with the group command
+Add hosts from a file with the import command, write them out with the export command
+
+The hosts file is JSON. Files in the old format, one host per line,
+are converted the next time the list changes.`,
 }
 
 func init() {
@@ -0,0 +1,261 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+	"github.com/spf13/cobra"
+)
+
+var ErrInvalidFormat = errors.New("invalid hosts format")
+
+// formats hosts are imported from and exported to
+const (
+	formatList  = "list"
+	formatHosts = "hosts"
+	formatJSON  = "json"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:          "import [file]",
+	Short:        "Add hosts from a file or STDIN",
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	Long: `Add the hosts in a file to the list, or those in STDIN without a
+file or with -.
+
+The file can be a list with one host per line, a file in the format of
+/etc/hosts, with an address followed by its names on each line, or the
+JSON results of a scan, as written by scan --output json. The format is
+detected from the content unless --format is given. Text after # is a
+comment in lists and hosts files.
+
+From hosts files, the addresses are added with their names as the
+description. From scan results, the hosts that were found are added.
+Use --group, --label, --description and --ports to record them for all
+the new hosts.
+
+Every host is checked before any is added. Hosts already in the list
+are skipped and reported, and the command then fails.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile, err := cmd.Flags().GetString("hosts-file")
+		if err != nil {
+			return err
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		info, err := hostInfo(cmd)
+		if err != nil {
+			return err
+		}
+
+		in := io.Reader(os.Stdin)
+		if len(args) == 1 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+
+		return importAction(os.Stdout, hostsFile, in, format, info)
+	},
+}
+
+func init() {
+	hostsCmd.AddCommand(importCmd)
+
+	importCmd.Flags().String("format", "", "format of the input: list, hosts or json, detected when not set")
+	addHostInfoFlags(importCmd)
+}
+
+// importedHost is a host read from an import, pos tells where it was
+// found
+type importedHost struct {
+	host        string
+	description string
+	pos         string
+}
+
+// importAction adds the hosts in in to the list, each with info and,
+// unless info has one, the description found with it
+func importAction(out io.Writer, hostsFile string, in io.Reader, format string, info scan.HostInfo) error {
+	hosts, err := readHosts(in, format)
+	if err != nil {
+		return err
+	}
+
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	var invalid, existing []error
+	added := 0
+	for _, h := range hosts {
+		err := hl.Add(h.host)
+		switch {
+		case errors.Is(err, scan.ErrExists):
+			existing = append(existing, err)
+			continue
+		case err != nil:
+			invalid = append(invalid, fmt.Errorf("%s: %w", h.pos, err))
+			continue
+		}
+
+		entryInfo := info
+		if entryInfo.Description == "" {
+			entryInfo.Description = h.description
+		}
+		hl.Update(h.host, func(i *scan.HostInfo) { *i = entryInfo })
+		added++
+	}
+
+	if len(invalid) > 0 {
+		return errors.Join(invalid...)
+	}
+
+	if added > 0 {
+		if err := hl.Save(hostsFile); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(out, "Imported %d hosts\n", added); err != nil {
+		return err
+	}
+
+	return errors.Join(existing...)
+}
+
+// readHosts reads the hosts in r, in format or, if it's empty, the
+// format detected from the content
+func readHosts(r io.Reader, format string) ([]importedHost, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		format = detectFormat(data)
+	}
+
+	switch format {
+	case formatList, formatHosts:
+		return readLines(data, format)
+	case formatJSON:
+		return readResults(data)
+	}
+
+	return nil, validateFormat(format)
+}
+
+func validateFormat(format string) error {
+	switch format {
+	case formatList, formatHosts, formatJSON:
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q, use one of list, hosts or json", ErrInvalidFormat, format)
+}
+
+// detectFormat tells scan results, starting with [, from hosts files,
+// with more than one field on the first line starting with an address,
+// and lists
+func detectFormat(data []byte) string {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return formatJSON
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := lineFields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if _, err := netip.ParseAddr(fields[0]); err == nil && len(fields) > 1 {
+			return formatHosts
+		}
+		break
+	}
+
+	return formatList
+}
+
+// lineFields returns the fields of line without its comment
+func lineFields(line string) []string {
+	line, _, _ = strings.Cut(line, "#")
+	return strings.Fields(line)
+}
+
+// readLines reads a list, one host per line, or a hosts file, with an
+// address followed by its names on each line
+func readLines(data []byte, format string) ([]importedHost, error) {
+	hosts := []importedHost{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		fields := lineFields(scanner.Text())
+		switch {
+		case len(fields) == 0:
+			continue
+		case format == formatList && len(fields) > 1:
+			return nil, fmt.Errorf("%w: line %d: expected one host per line", ErrInvalidFormat, n)
+		case format == formatList:
+			hosts = append(hosts, importedHost{host: fields[0], pos: fmt.Sprintf("line %d", n)})
+			continue
+		}
+
+		if _, err := netip.ParseAddr(fields[0]); err != nil || len(fields) < 2 {
+			return nil, fmt.Errorf("%w: line %d: expected an address followed by names", ErrInvalidFormat, n)
+		}
+
+		hosts = append(hosts, importedHost{
+			host:        fields[0],
+			description: strings.Join(fields[1:], " "),
+			pos:         fmt.Sprintf("line %d", n),
+		})
+	}
+
+	return hosts, scanner.Err()
+}
+
+// readResults reads the hosts found in JSON scan results, once each
+// when they were scanned on several addresses
+func readResults(data []byte) ([]importedHost, error) {
+	var views []hostView
+	if err := json.Unmarshal(data, &views); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFormat, err)
+	}
+
+	hosts := []importedHost{}
+	seen := map[string]bool{}
+	for i, v := range views {
+		if v.NotFound || seen[v.Host] {
+			continue
+		}
+
+		seen[v.Host] = true
+		hosts = append(hosts, importedHost{host: v.Host, pos: fmt.Sprintf("result %d", i+1)})
+	}
+
+	return hosts, nil
+}
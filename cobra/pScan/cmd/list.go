@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/bedminer1/cobra/pScan/scan"
 	"github.com/spf13/cobra"
@@ -17,6 +19,12 @@ var listCmd = &cobra.Command{
 	Use:   "list",
 	Aliases: []string{"l"},
 	Short: "List hosts in hosts list",
+	Long: `List the entries in the hosts list.
+
+CIDR blocks, address ranges and name patterns show how many hosts they
+stand for, followed by the groups, labels, ports and description of
+the entries that have them. The last line has the number of entries
+and of hosts they expand to, without duplicates.`,
 
 	RunE: func(cmd *cobra.Command, args []string) error{
 		hostsFile, err := cmd.Flags().GetString("hosts-file")
@@ -38,10 +46,44 @@ func listAction(out io.Writer, hostsFile string, args []string) error {
 	}
 
 	for _, h := range hl.Hosts {
-		if _, err := fmt.Fprintln(out, h); err != nil {
+		line := h
+		if n := scan.EntrySize(h); n != 1 {
+			line = fmt.Sprintf("%s (%d hosts)", h, n)
+		}
+		line += infoDetail(hl.Info[h])
+
+		if _, err := fmt.Fprintln(out, line); err != nil {
 			return err
 		}
 	}
 
-	return nil
-}
\ No newline at end of file
+	_, err := fmt.Fprintf(out, "%d entries, %d hosts\n", len(hl.Hosts), len(hl.Expand()))
+	return err
+}
+
+// infoDetail returns the groups, labels, ports and description in info
+func infoDetail(info scan.HostInfo) string {
+	detail := ""
+	if len(info.Groups) > 0 {
+		detail += fmt.Sprintf(" [%s]", strings.Join(info.Groups, ","))
+	}
+
+	keys := make([]string, 0, len(info.Labels))
+	for k := range info.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		detail += fmt.Sprintf(" %s=%s", k, info.Labels[k])
+	}
+
+	if info.Ports != "" {
+		detail += " ports=" + info.Ports
+	}
+
+	if info.Description != "" {
+		detail += " - " + info.Description
+	}
+
+	return detail
+}
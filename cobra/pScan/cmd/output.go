@@ -0,0 +1,553 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+)
+
+var ErrInvalidOutput = errors.New("invalid output format")
+
+// supported values for the --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputCSV  = "csv"
+	outputXML  = "xml"
+)
+
+// scanInfo describes a scan run for the output formats that record it
+type scanInfo struct {
+	Args  []string
+	Ports []int
+	UDP   bool
+	// TLS is set when open ports were inspected for TLS
+	TLS   bool
+	Start time.Time
+	End   time.Time
+	// Err is the error of the context when the scan was stopped before
+	// the end
+	Err error
+}
+
+func validateOutput(format string) error {
+	switch format {
+	case outputText, outputJSON, outputCSV, outputXML:
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q, use one of text, json, csv or xml", ErrInvalidOutput, format)
+}
+
+// writeResults writes results to out in format
+func writeResults(out io.Writer, format string, info scanInfo, results []scan.Results) error {
+	switch format {
+	case "", outputText:
+		return printResults(out, results)
+	case outputJSON:
+		return writeJSON(out, info, results)
+	case outputCSV:
+		return writeCSV(out, info, results)
+	case outputXML:
+		return writeXML(out, info, results)
+	}
+
+	return validateOutput(format)
+}
+
+type hostView struct {
+	Host     string     `json:"host"`
+	Addrs    []string   `json:"addresses,omitempty"`
+	Addr     string     `json:"address,omitempty"`
+	Names    []string   `json:"names,omitempty"`
+	NotFound bool       `json:"not_found"`
+	Down     bool       `json:"down,omitempty"`
+	Ports    []portView `json:"ports"`
+	TLS      []tlsView  `json:"tls,omitempty"`
+	// Interrupted is set when the scan was stopped before the end, so
+	// the ports may be incomplete
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+type portView struct {
+	Port      int     `json:"port"`
+	Protocol  string  `json:"protocol"`
+	State     string  `json:"state"`
+	LatencyMS float64 `json:"latency_ms"`
+	Service   string  `json:"service,omitempty"`
+	Version   string  `json:"version,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func newPortView(p scan.PortState) portView {
+	v := portView{
+		Port:      p.Port,
+		Protocol:  p.Proto,
+		State:     p.State.String(),
+		LatencyMS: latencyMS(p.Latency),
+		Service:   p.Service,
+		Version:   p.Version,
+	}
+
+	if p.Err != nil {
+		v.Error = p.Err.Error()
+	}
+
+	return v
+}
+
+type tlsView struct {
+	Port          int       `json:"port"`
+	Version       string    `json:"version"`
+	Cipher        string    `json:"cipher"`
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	SANs          []string  `json:"sans"`
+	NotAfter      time.Time `json:"not_after"`
+	Expired       bool      `json:"expired"`
+	Expiring      bool      `json:"expiring"`
+	HostnameValid bool      `json:"hostname_valid"`
+	Warnings      []string  `json:"warnings,omitempty"`
+}
+
+func newTLSView(p scan.PortState) tlsView {
+	return tlsView{
+		Port:          p.Port,
+		Version:       p.TLS.Version,
+		Cipher:        p.TLS.Cipher,
+		Subject:       p.TLS.Subject,
+		Issuer:        p.TLS.Issuer,
+		SANs:          p.TLS.SANs,
+		NotAfter:      p.TLS.NotAfter,
+		Expired:       p.TLS.Expired,
+		Expiring:      p.TLS.Expiring,
+		HostnameValid: p.TLS.HostnameErr == "",
+		Warnings:      tlsWarnings(p.TLS),
+	}
+}
+
+// tlsWarnings returns what is wrong with the certificate in info
+func tlsWarnings(info *scan.TLSInfo) []string {
+	var warnings []string
+	switch {
+	case info.Expired:
+		warnings = append(warnings, "certificate expired")
+	case info.Expiring:
+		warnings = append(warnings, "certificate expiring soon")
+	}
+
+	if info.HostnameErr != "" {
+		warnings = append(warnings, "hostname mismatch: "+info.HostnameErr)
+	}
+
+	return warnings
+}
+
+// latencyMS returns d in milliseconds, rounded to the microsecond
+func latencyMS(d time.Duration) float64 {
+	return float64(d.Round(time.Microsecond)) / float64(time.Millisecond)
+}
+
+func writeJSON(out io.Writer, info scanInfo, results []scan.Results) error {
+	views := newHostViews(results)
+	for i := range views {
+		views[i].Interrupted = info.Err != nil
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(views)
+}
+
+func newHostViews(results []scan.Results) []hostView {
+	views := make([]hostView, 0, len(results))
+	for _, r := range results {
+		v := hostView{
+			Host:     r.Host,
+			Addrs:    r.Addrs,
+			Addr:     r.Addr,
+			Names:    r.Names,
+			NotFound: r.NotFound,
+			Down:     r.Down,
+			Ports:    []portView{},
+		}
+		for _, p := range r.PortStates {
+			v.Ports = append(v.Ports, newPortView(p))
+			if p.TLS != nil {
+				v.TLS = append(v.TLS, newTLSView(p))
+			}
+		}
+		views = append(views, v)
+	}
+
+	return views
+}
+
+// scannedAddrs returns the address r was scanned on, or all its
+// addresses if the connections were made to its name
+func scannedAddrs(r scan.Results) []string {
+	if r.Addr != "" {
+		return []string{r.Addr}
+	}
+
+	return r.Addrs
+}
+
+// csvTLSColumns are added to the CSV output of scans inspecting TLS
+var csvTLSColumns = []string{
+	"tls_version", "tls_cipher", "cert_subject", "cert_issuer",
+	"cert_sans", "cert_expiry", "cert_warnings",
+}
+
+// writeCSV writes one row per port, and one row with the state
+// "not found" for hosts that didn't resolve or "down" for hosts that
+// didn't answer the ping. Addresses are separated by spaces. Scans
+// inspecting TLS get the certificate columns too
+func writeCSV(out io.Writer, info scanInfo, results []scan.Results) error {
+	w := csv.NewWriter(out)
+	header := []string{
+		"host", "addresses", "port", "protocol", "state",
+		"latency_ms", "service", "version", "error",
+	}
+	if info.TLS {
+		header = append(header, csvTLSColumns...)
+	}
+	w.Write(header)
+
+	for _, r := range results {
+		if r.NotFound {
+			row := []string{r.Host, "", "", "", "not found", "", "", "", ""}
+			if info.TLS {
+				row = append(row, make([]string, len(csvTLSColumns))...)
+			}
+			w.Write(row)
+			continue
+		}
+
+		addrs := strings.Join(scannedAddrs(r), " ")
+		if r.Down {
+			row := []string{r.Host, addrs, "", "", "down", "", "", "", ""}
+			if info.TLS {
+				row = append(row, make([]string, len(csvTLSColumns))...)
+			}
+			w.Write(row)
+			continue
+		}
+
+		for _, p := range r.PortStates {
+			v := newPortView(p)
+			row := []string{
+				r.Host,
+				addrs,
+				strconv.Itoa(v.Port),
+				v.Protocol,
+				v.State,
+				strconv.FormatFloat(v.LatencyMS, 'f', -1, 64),
+				v.Service,
+				v.Version,
+				v.Error,
+			}
+			if info.TLS {
+				row = append(row, csvTLS(p.TLS)...)
+			}
+			w.Write(row)
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// csvTLS returns the certificate columns for a port with TLS info, or
+// empty ones
+func csvTLS(info *scan.TLSInfo) []string {
+	if info == nil {
+		return make([]string, len(csvTLSColumns))
+	}
+
+	return []string{
+		info.Version,
+		info.Cipher,
+		info.Subject,
+		info.Issuer,
+		strings.Join(info.SANs, " "),
+		info.NotAfter.UTC().Format(time.RFC3339),
+		strings.Join(tlsWarnings(info), "; "),
+	}
+}
+
+// nmap XML output, only the elements and attributes pScan has data for
+
+type nmapRun struct {
+	XMLName          xml.Name     `xml:"nmaprun"`
+	Scanner          string       `xml:"scanner,attr"`
+	Args             string       `xml:"args,attr"`
+	Start            int64        `xml:"start,attr"`
+	StartStr         string       `xml:"startstr,attr"`
+	Version          string       `xml:"version,attr"`
+	XMLOutputVersion string       `xml:"xmloutputversion,attr"`
+	ScanInfo         nmapScanInfo `xml:"scaninfo"`
+	Hosts            []nmapHost   `xml:"host"`
+	RunStats         nmapRunStats `xml:"runstats"`
+}
+
+type nmapScanInfo struct {
+	Type        string `xml:"type,attr"`
+	Protocol    string `xml:"protocol,attr"`
+	NumServices int    `xml:"numservices,attr"`
+	Services    string `xml:"services,attr"`
+}
+
+type nmapHost struct {
+	Status    nmapStatus     `xml:"status"`
+	Addresses []nmapAddress  `xml:"address"`
+	Hostnames []nmapHostname `xml:"hostnames>hostname"`
+	Ports     *nmapPorts     `xml:"ports"`
+}
+
+type nmapPorts struct {
+	Ports []nmapPort `xml:"port"`
+}
+
+type nmapStatus struct {
+	State  string `xml:"state,attr"`
+	Reason string `xml:"reason,attr"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPort struct {
+	Protocol string       `xml:"protocol,attr"`
+	PortID   int          `xml:"portid,attr"`
+	State    nmapState    `xml:"state"`
+	Service  *nmapService `xml:"service"`
+	Scripts  []nmapScript `xml:"script"`
+}
+
+// nmapScript is the output of an nmap script, pScan reports TLS
+// certificates like ssl-cert
+type nmapScript struct {
+	ID     string     `xml:"id,attr"`
+	Output string     `xml:"output,attr"`
+	Elems  []nmapElem `xml:"elem"`
+}
+
+type nmapElem struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func newSSLCertScript(info *scan.TLSInfo) nmapScript {
+	notAfter := info.NotAfter.UTC().Format("2006-01-02T15:04:05")
+	s := nmapScript{
+		ID: "ssl-cert",
+		Output: fmt.Sprintf("Subject: %s\nSubject Alternative Name: %s\nIssuer: %s\nNot valid after:  %s",
+			info.Subject, strings.Join(info.SANs, ", "), info.Issuer, notAfter),
+		Elems: []nmapElem{
+			{Key: "subject", Value: info.Subject},
+			{Key: "issuer", Value: info.Issuer},
+			{Key: "sans", Value: strings.Join(info.SANs, ", ")},
+			{Key: "notAfter", Value: notAfter},
+			{Key: "protocol", Value: info.Version},
+			{Key: "cipher", Value: info.Cipher},
+		},
+	}
+
+	for _, w := range tlsWarnings(info) {
+		s.Elems = append(s.Elems, nmapElem{Key: "warning", Value: w})
+	}
+
+	return s
+}
+
+type nmapState struct {
+	State  string `xml:"state,attr"`
+	Reason string `xml:"reason,attr"`
+	// RTT is the latency in microseconds, an attribute nmap doesn't
+	// have, parsers ignore it
+	RTT int64 `xml:"rtt,attr,omitempty"`
+}
+
+type nmapService struct {
+	Name    string `xml:"name,attr"`
+	Product string `xml:"product,attr,omitempty"`
+	Method  string `xml:"method,attr"`
+	Conf    int    `xml:"conf,attr"`
+}
+
+type nmapRunStats struct {
+	Finished nmapFinished `xml:"finished"`
+	Hosts    nmapHosts    `xml:"hosts"`
+}
+
+type nmapFinished struct {
+	Time     int64   `xml:"time,attr"`
+	TimeStr  string  `xml:"timestr,attr"`
+	Elapsed  float64 `xml:"elapsed,attr"`
+	Exit     string  `xml:"exit,attr"`
+	ErrorMsg string  `xml:"errormsg,attr,omitempty"`
+}
+
+type nmapHosts struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+// nmapReason returns the reason nmap gives for a port state
+func nmapReason(p scan.PortState) string {
+	switch {
+	case p.State == scan.StateOpen && p.Proto == scan.ProtoUDP:
+		return "udp-response"
+	case p.State == scan.StateOpen:
+		return "syn-ack"
+	case p.State == scan.StateClosed && p.Proto == scan.ProtoUDP:
+		return "port-unreach"
+	case p.State == scan.StateClosed:
+		return "conn-refused"
+	case p.State == scan.StateError:
+		return "error"
+	}
+
+	return "no-response"
+}
+
+func newNmapHost(r scan.Results) nmapHost {
+	h := nmapHost{
+		Status:    nmapStatus{State: "up", Reason: "user-set"},
+		Hostnames: []nmapHostname{{Name: r.Host, Type: "user"}},
+	}
+
+	switch {
+	case r.NotFound:
+		h.Status = nmapStatus{State: "down", Reason: "no-dns"}
+	case r.Down:
+		h.Status = nmapStatus{State: "down", Reason: "no-response"}
+	}
+
+	for _, n := range r.Names {
+		h.Hostnames = append(h.Hostnames, nmapHostname{Name: n, Type: "PTR"})
+	}
+
+	for _, a := range scannedAddrs(r) {
+		addrType := "ipv4"
+		if ip := net.ParseIP(a); ip != nil && ip.To4() == nil {
+			addrType = "ipv6"
+		}
+		h.Addresses = append(h.Addresses, nmapAddress{Addr: a, AddrType: addrType})
+	}
+
+	if len(r.PortStates) > 0 {
+		h.Ports = &nmapPorts{}
+	}
+
+	for _, p := range r.PortStates {
+		np := nmapPort{
+			Protocol: p.Proto,
+			PortID:   p.Port,
+			State: nmapState{
+				State:  p.State.String(),
+				Reason: nmapReason(p),
+				RTT:    p.Latency.Microseconds(),
+			},
+		}
+
+		if p.Service != "" {
+			np.Service = &nmapService{
+				Name:    p.Service,
+				Product: p.Version,
+				Method:  "probed",
+				Conf:    10,
+			}
+		}
+
+		if p.TLS != nil {
+			np.Scripts = append(np.Scripts, newSSLCertScript(p.TLS))
+		}
+
+		h.Ports.Ports = append(h.Ports.Ports, np)
+	}
+
+	return h
+}
+
+func writeXML(out io.Writer, info scanInfo, results []scan.Results) error {
+	scanType, protocol := "connect", scan.ProtoTCP
+	if info.UDP {
+		scanType, protocol = "udp", scan.ProtoUDP
+	}
+
+	services := make([]string, 0, len(info.Ports))
+	for _, p := range info.Ports {
+		services = append(services, strconv.Itoa(p))
+	}
+
+	run := nmapRun{
+		Scanner:          "pScan",
+		Args:             strings.Join(info.Args, " "),
+		Start:            info.Start.Unix(),
+		StartStr:         info.Start.Format(time.ANSIC),
+		Version:          rootCmd.Version,
+		XMLOutputVersion: "1.05",
+		ScanInfo: nmapScanInfo{
+			Type:        scanType,
+			Protocol:    protocol,
+			NumServices: len(info.Ports),
+			Services:    strings.Join(services, ","),
+		},
+		RunStats: nmapRunStats{
+			Finished: nmapFinished{
+				Time:    info.End.Unix(),
+				TimeStr: info.End.Format(time.ANSIC),
+				Elapsed: info.End.Sub(info.Start).Round(time.Millisecond).Seconds(),
+				Exit:    "success",
+			},
+		},
+	}
+
+	if info.Err != nil {
+		run.RunStats.Finished.Exit = "error"
+		run.RunStats.Finished.ErrorMsg = fmt.Sprintf("%s: %s", ErrInterrupted, info.Err)
+	}
+
+	for _, r := range results {
+		h := newNmapHost(r)
+		if r.NotFound || r.Down {
+			run.RunStats.Hosts.Down++
+		} else {
+			run.RunStats.Hosts.Up++
+		}
+		run.Hosts = append(run.Hosts, h)
+	}
+	run.RunStats.Hosts.Total = len(results)
+
+	if _, err := io.WriteString(out, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(out)
+	return err
+}
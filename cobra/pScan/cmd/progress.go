@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// progressBar draws the progress of a scan on a line of a terminal. A
+// nil progressBar draws nothing
+type progressBar struct {
+	w     io.Writer
+	width int
+	done  int
+	total int
+	// drawn is the line on the terminal, if any
+	drawn string
+}
+
+func newProgressBar(w io.Writer) *progressBar {
+	if w == nil {
+		return nil
+	}
+
+	return &progressBar{w: w, width: 30}
+}
+
+// update draws the bar with done of total ports scanned
+func (b *progressBar) update(done, total int) {
+	if b == nil {
+		return
+	}
+
+	b.done, b.total = done, total
+	b.draw()
+}
+
+func (b *progressBar) draw() {
+	if b == nil || b.total == 0 {
+		return
+	}
+
+	filled := b.width * b.done / b.total
+	line := fmt.Sprintf("[%s%s] %d/%d ports",
+		strings.Repeat("=", filled), strings.Repeat(" ", b.width-filled), b.done, b.total)
+	if line == b.drawn {
+		return
+	}
+
+	fmt.Fprintf(b.w, "\r%s", line)
+	b.drawn = line
+}
+
+// clear erases the bar, so something else can be written
+func (b *progressBar) clear() {
+	if b == nil || b.drawn == "" {
+		return
+	}
+
+	fmt.Fprintf(b.w, "\r%s\r", strings.Repeat(" ", len(b.drawn)))
+	b.drawn = ""
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
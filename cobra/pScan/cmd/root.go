@@ -16,6 +16,7 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -28,15 +29,29 @@ var rootCmd = &cobra.Command{
 	Long: `Executes TCP port scan on a list of hosts. 
 	
 	pScan allows you to add, list, and delete hosts from the list.
+	
+	Settings are read from flags, PSCAN_* environment variables and the
+	config file, which can hold scan profiles, see docs/pScan.md.
 	`,
 	Version: "0.1",
 
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return initConfig(cmd)
+	},
+
 	// Run: func(cmd *cobra.Command, args []string) { },
 }
 
 func Execute() {
-	err := rootCmd.Execute()
+	cmd, err := rootCmd.ExecuteC()
+	if errors.Is(err, ErrChanged) {
+		os.Exit(exitChanged)
+	}
 	if err != nil {
+		// commands silencing errors leave the real ones to print here
+		if cmd.SilenceErrors {
+			cmd.PrintErrln(cmd.ErrPrefix(), err.Error())
+		}
 		os.Exit(1)
 	}
 }
@@ -44,6 +59,8 @@ func Execute() {
 func init() {
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	rootCmd.PersistentFlags().StringP("hosts-file", "f", "pScan.hosts", "pScan hosts file")
+	rootCmd.PersistentFlags().String("history-file", "pScan.db", "pScan scan history database")
+	rootCmd.PersistentFlags().String("config", "", "config file (default $HOME/.pScan.yaml)")
 
 	// templating for -version info
 	versionTemplate := `{{printf "%s: %s - version %s\n" .Name .Short .Version}}`
@@ -4,9 +4,16 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/bedminer1/cobra/pScan/scan"
 	"github.com/spf13/cobra"
@@ -14,8 +21,68 @@ import (
 
 // scanCmd represents the scan command
 var scanCmd = &cobra.Command{
-	Use:   "scan",
-	Short: "Run a port scan on the hosts",
+	Use:          "scan",
+	Short:        "Run a port scan on the hosts",
+	SilenceUsage: true,
+	Long: `Run a port scan on the hosts.
+
+Ports are given as a comma separated list of ports, ranges and named
+sets, e.g. "22,80-90,web". The named sets are top100, web and db.
+Use --top-ports N to scan the N most common ports instead, and
+--exclude-ports to leave some out.
+
+TCP ports are reported as open, closed when the connection is refused,
+filtered when it times out or is blocked, or error when the scan itself
+failed, with the time the connection took.
+
+With --udp, UDP ports are probed instead, using DNS, NTP and SNMP
+requests on their ports and empty datagrams elsewhere. Ports that
+reply are open, ports answering with ICMP port unreachable are closed
+and silent ports are reported as open|filtered. Without --ports, the
+UDP scan checks 53, 123 and 161.
+
+With --service-detect, open ports are probed to find out what runs on
+them. pScan reads the banner of services that greet clients, like SSH,
+SMTP or FTP, then tries a TLS handshake and an HTTP HEAD request, and
+reports the service name and version it recognises.
+
+With --tls-inspect, pScan completes a TLS handshake with open TCP
+ports and reports the protocol version, cipher and the subject, SANs,
+issuer and expiry date of the certificate. Certificates that expired,
+expire within --tls-expiry-days or aren't valid for the host name are
+flagged. The TLS details have their own section in every output
+format.
+
+Hosts with ports of their own in the hosts list, or in a group with
+default ports, are scanned on those unless --ports or --top-ports is
+given. --exclude-ports applies to them too, UDP scans don't use them.
+Use --group and --label key=value to scan only the hosts in any of the
+groups that have all the labels.
+
+Hosts are looked up with the system's resolver, or with the DNS server
+given with --resolver. Each host is scanned on one of its addresses,
+or on all of them with --all-addrs, and --prefer ipv4 or ipv6 picks
+which family comes first. With --reverse, the names the addresses
+resolve back to are looked up too. The addresses and names are shown
+with the results.
+
+With --skip-down, the hosts are pinged before the scan, like discover
+does, and those that don't answer are reported as down without
+scanning their ports. Use --ping-ports and --icmp to set how.
+
+Each scan is saved in the history file, see the history and diff
+commands, unless --no-history is given.
+
+Text results are printed as soon as every port of a host is scanned,
+with a progress bar on the terminal. On Ctrl-C, or when --max-time
+runs out, the scan stops and the results so far are written. Stopped
+scans are not saved in the history. Their json output marks each host
+"interrupted" and their xml output ends with exit="error".
+
+Use --output to get the results as json, csv or nmap compatible xml
+instead of text, and --output-file to write them to a file. The file
+is only replaced once the results are written, so a scan that fails
+leaves it as it was.`,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hostsFile, err := cmd.Flags().GetString("hosts-file")
@@ -23,43 +90,545 @@ var scanCmd = &cobra.Command{
 			return err
 		}
 
-		ports, err := cmd.Flags().GetIntSlice("ports")
+		sel, opts, err := scanSettings(cmd)
 		if err != nil {
 			return err
 		}
 
-		return scanAction(os.Stdout, hostsFile, ports)
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+
+		if err := validateOutput(output); err != nil {
+			return err
+		}
+
+		outputFile, err := cmd.Flags().GetString("output-file")
+		if err != nil {
+			return err
+		}
+
+		historyFile, err := cmd.Flags().GetString("history-file")
+		if err != nil {
+			return err
+		}
+
+		noHistory, err := cmd.Flags().GetBool("no-history")
+		if err != nil {
+			return err
+		}
+
+		if noHistory {
+			historyFile = ""
+		}
+
+		maxTime, err := cmd.Flags().GetDuration("max-time")
+		if err != nil {
+			return err
+		}
+
+		noProgress, err := cmd.Flags().GetBool("no-progress")
+		if err != nil {
+			return err
+		}
+
+		cfg := scanConfig{
+			hostsFile:   hostsFile,
+			historyFile: historyFile,
+			sel:         sel,
+			opts:        opts,
+			output:      output,
+		}
+
+		if !noProgress && isTerminal(os.Stderr) {
+			cfg.progress = os.Stderr
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if maxTime > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, maxTime)
+			defer cancel()
+		}
+
+		if outputFile == "" {
+			return scanAction(ctx, os.Stdout, cfg)
+		}
+
+		return writeFile(outputFile, func(out io.Writer) error {
+			return scanAction(ctx, out, cfg)
+		})
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(scanCmd)
 
-	scanCmd.Flags().IntSliceP("ports", "p", []int{22, 80, 443}, "ports to scan")
+	addScanFlags(scanCmd)
+	scanCmd.Flags().StringP("output", "o", outputText, "output format: text, json, csv or xml")
+	scanCmd.Flags().String("output-file", "", "write the results to this file instead of stdout")
+	scanCmd.Flags().Bool("no-history", false, "don't save the scan in the history file")
+	scanCmd.Flags().Duration("max-time", 0, "stop the scan after this long, 0 for no limit")
+	scanCmd.Flags().Bool("no-progress", false, "don't show a progress bar")
+}
+
+// addScanFlags adds the flags choosing the hosts and ports to scan and
+// how to scan them
+func addScanFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("profile", "P", "", "scan profile from the config file")
+	cmd.Flags().StringP("ports", "p", "22,80,443", "ports to scan, e.g. 22,80-90,web")
+	cmd.Flags().String("exclude-ports", "", "ports not to scan")
+	cmd.Flags().Int("top-ports", 0, "scan the N most common ports")
+	cmd.Flags().IntP("workers", "w", scan.DefaultWorkers, "number of concurrent connections")
+	cmd.Flags().Int("rate", 0, "maximum connections per second, 0 for no limit")
+	cmd.Flags().DurationP("timeout", "t", scan.DefaultTimeout, "timeout for each connection")
+	cmd.Flags().Bool("udp", false, "scan UDP ports instead of TCP")
+	cmd.Flags().Bool("service-detect", false, "detect the service and version on open ports")
+	cmd.Flags().Bool("tls-inspect", false, "report the TLS certificate of open ports")
+	cmd.Flags().Int("tls-expiry-days", 30, "flag certificates expiring within this many days")
+	cmd.Flags().StringSliceP("group", "g", nil, "scan only the hosts in these groups")
+	cmd.Flags().StringSliceP("label", "l", nil, "scan only the hosts with these labels, as key=value")
+	cmd.Flags().String("resolver", "", "DNS server to look up the hosts with, as address[:port]")
+	cmd.Flags().String("prefer", "", "address family to scan first, ipv4 or ipv6")
+	cmd.Flags().Bool("all-addrs", false, "scan every address of each host")
+	cmd.Flags().Bool("reverse", false, "look up the names of the addresses of each host")
+	cmd.Flags().Bool("skip-down", false, "ping the hosts first and skip those that don't answer")
+	addPingFlags(cmd)
+}
+
+// addPingFlags adds the flags setting how hosts are pinged
+func addPingFlags(cmd *cobra.Command) {
+	cmd.Flags().String("ping-ports", defaultPingPorts, "ports of the TCP ping")
+	cmd.Flags().Bool("icmp", false, "ping with ICMP echo too, needs root privileges")
 }
 
-func scanAction(out io.Writer, hostsFile string, ports []int) error {
+// scanSettings returns the hosts and ports to scan and the scan
+// options set with the flags added by addScanFlags
+func scanSettings(cmd *cobra.Command) (selection, scan.Options, error) {
+	portSpec, err := cmd.Flags().GetString("ports")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	excludeSpec, err := cmd.Flags().GetString("exclude-ports")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	top, err := cmd.Flags().GetInt("top-ports")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	udp, err := cmd.Flags().GetBool("udp")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	serviceDetect, err := cmd.Flags().GetBool("service-detect")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	// ports set from the config are defaults: hosts with ports of their
+	// own keep them and UDP scans use the UDP ports
+	portsGiven := flagGiven(cmd, "ports")
+	if udp && !portsGiven {
+		portSpec = defaultUDPPorts
+	}
+
+	if top > 0 && portsGiven {
+		return selection{}, scan.Options{}, fmt.Errorf("%w: use either --ports or --top-ports", scan.ErrInvalidPort)
+	}
+
+	ports, err := scanPorts(portSpec, excludeSpec, top)
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	sel := selection{
+		ports: ports,
+		fixed: udp || portsGiven || flagGiven(cmd, "top-ports"),
+	}
+
+	if excludeSpec != "" {
+		if sel.exclude, err = scan.ParsePorts(excludeSpec); err != nil {
+			return selection{}, scan.Options{}, err
+		}
+	}
+
+	if sel.groups, err = cmd.Flags().GetStringSlice("group"); err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	labels, err := cmd.Flags().GetStringSlice("label")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	if sel.labels, err = scan.ParseLabels(labels); err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	workers, err := cmd.Flags().GetInt("workers")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	rate, err := cmd.Flags().GetInt("rate")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	if err := scan.ValidateRate(rate); err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	tlsInspect, err := cmd.Flags().GetBool("tls-inspect")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	expiryDays, err := cmd.Flags().GetInt("tls-expiry-days")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	resolver, err := cmd.Flags().GetString("resolver")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	if resolver != "" {
+		if resolver, err = scan.ResolverAddress(resolver); err != nil {
+			return selection{}, scan.Options{}, err
+		}
+	}
+
+	prefer, err := cmd.Flags().GetString("prefer")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	if err := scan.ValidatePrefer(prefer); err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	allAddrs, err := cmd.Flags().GetBool("all-addrs")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	reverse, err := cmd.Flags().GetBool("reverse")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	skipDown, err := cmd.Flags().GetBool("skip-down")
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	pingPorts, icmp, err := pingSettings(cmd)
+	if err != nil {
+		return selection{}, scan.Options{}, err
+	}
+
+	opts := scan.Options{
+		Workers: workers,
+		Rate:    rate,
+		Timeout: timeout,
+		UDP:     udp,
+
+		ServiceDetect: serviceDetect,
+		TLSInspect:    tlsInspect,
+		ExpiryDays:    expiryDays,
+		Resolver:      resolver,
+		Prefer:        prefer,
+		AllAddrs:      allAddrs,
+		ReverseLookup: reverse,
+		SkipDown:      skipDown,
+		PingPorts:     pingPorts,
+		ICMP:          icmp,
+	}
+
+	return sel, opts, nil
+}
+
+// pingSettings returns the ping ports and whether to send an ICMP echo
+// set with the flags added by addPingFlags
+func pingSettings(cmd *cobra.Command) ([]int, bool, error) {
+	spec, err := cmd.Flags().GetString("ping-ports")
+	if err != nil {
+		return nil, false, err
+	}
+
+	ports, err := scan.ParsePorts(spec)
+	if err != nil {
+		return nil, false, err
+	}
+
+	icmp, err := cmd.Flags().GetBool("icmp")
+	if err != nil {
+		return nil, false, err
+	}
+
+	return ports, icmp, nil
+}
+
+// ports scanned with --udp when --ports isn't set
+const defaultUDPPorts = "53,123,161"
+
+// ports of the TCP ping when --ping-ports isn't set, the same as
+// scan.DefaultPingPorts
+const defaultPingPorts = "80,443,22,445,3389"
+
+// scanPorts resolves the ports to scan from the port flags
+func scanPorts(portSpec, excludeSpec string, top int) ([]int, error) {
+	var ports []int
+	var err error
+	if top > 0 {
+		ports, err = scan.TopPorts(top)
+	} else {
+		ports, err = scan.ParsePorts(portSpec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if excludeSpec == "" {
+		return ports, nil
+	}
+
+	exclude, err := scan.ParsePorts(excludeSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	ports = scan.ExcludePorts(ports, exclude)
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("%w: all ports excluded", scan.ErrInvalidPort)
+	}
+
+	return ports, nil
+}
+
+// selection is what to scan: the hosts in any of groups with all
+// labels, on the ports set for each host or, for hosts without them or
+// when fixed, on ports
+type selection struct {
+	groups  []string
+	labels  map[string]string
+	ports   []int
+	exclude []int
+	fixed   bool
+}
+
+var ErrInterrupted = errors.New("scan interrupted")
+
+// scanConfig is what scanAction scans, how, and where the results go
+type scanConfig struct {
+	hostsFile string
+	// historyFile, unless empty, is where the scan is saved
+	historyFile string
+	sel         selection
+	opts        scan.Options
+	output      string
+	// progress, unless nil, gets a progress bar
+	progress io.Writer
+}
+
+// scanAction scans the hosts in cfg.sel and writes the results to out.
+// Text results are written as they arrive. When ctx is done before the
+// end, the results so far are written and ErrInterrupted is returned
+func scanAction(ctx context.Context, out io.Writer, cfg scanConfig) error {
+	bar := newProgressBar(cfg.progress)
+	opts := cfg.opts
+	opts.OnProgress = bar.update
+
+	stream := cfg.output == "" || cfg.output == outputText
+	printed := map[int]bool{}
+	var printErr error
+	if stream {
+		// print the hosts in order, each once the ones before are done
+		ready := map[int]scan.Results{}
+		next := 0
+		opts.OnResult = func(i int, r scan.Results) {
+			ready[i] = r
+			bar.clear()
+			for r, ok := ready[next]; ok; r, ok = ready[next] {
+				delete(ready, next)
+				next++
+				printed[next-1] = true
+				if err := printResults(out, []scan.Results{r}); err != nil && printErr == nil {
+					printErr = err
+				}
+			}
+			bar.draw()
+		}
+	}
+
+	info, results, err := runScan(ctx, cfg.hostsFile, cfg.sel, opts)
+	bar.clear()
+	interrupted := errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+	if err != nil && !interrupted {
+		return err
+	}
+
+	if stream {
+		rest := []scan.Results{}
+		for i, r := range results {
+			if !printed[i] {
+				rest = append(rest, r)
+			}
+		}
+
+		if err := printResults(out, rest); err != nil && printErr == nil {
+			printErr = err
+		}
+		if printErr != nil {
+			return printErr
+		}
+	} else if err := writeResults(out, cfg.output, info, results); err != nil {
+		return err
+	}
+
+	if interrupted {
+		return fmt.Errorf("%w: %w", ErrInterrupted, err)
+	}
+
+	if cfg.historyFile == "" {
+		return nil
+	}
+
+	return saveRun(cfg.historyFile, info, results)
+}
+
+// runScan scans the hosts in sel from hostsFile. When ctx is done
+// before the end, it returns the results so far and the error of ctx
+func runScan(ctx context.Context, hostsFile string, sel selection, opts scan.Options) (scanInfo, []scan.Results, error) {
 	hl := &scan.HostsList{}
 	if err := hl.Load(hostsFile); err != nil {
+		return scanInfo{}, nil, err
+	}
+
+	if len(sel.groups) > 0 || len(sel.labels) > 0 {
+		hl = hl.Filter(sel.groups, sel.labels)
+		if len(hl.Hosts) == 0 {
+			return scanInfo{}, nil, fmt.Errorf("%w: no hosts match the groups and labels", scan.ErrNotExists)
+		}
+	}
+
+	targets := hl.Targets(sel.ports)
+	if !sel.fixed {
+		var err error
+		if targets, err = hl.HostTargets(sel.ports, sel.exclude); err != nil {
+			return scanInfo{}, nil, err
+		}
+	}
+
+	info := scanInfo{
+		Args:  os.Args,
+		Ports: targetPorts(sel.ports, targets),
+		UDP:   opts.UDP,
+		TLS:   opts.TLSInspect,
+		Start: time.Now(),
+	}
+
+	results, err := scan.RunContext(ctx, targets, opts)
+	info.End = time.Now()
+	info.Err = err
+
+	return info, results, err
+}
+
+// targetPorts returns the ports scanned on any of targets, or ports if
+// there are no targets
+func targetPorts(ports []int, targets []scan.Target) []int {
+	if len(targets) == 0 {
+		return ports
+	}
+
+	res := []int{}
+	seen := map[int]bool{}
+	for _, t := range targets {
+		for _, p := range t.Ports {
+			if !seen[p] {
+				seen[p] = true
+				res = append(res, p)
+			}
+		}
+	}
+
+	return res
+}
+
+// writeFile runs action with file as its output. The output goes to a
+// temporary file renamed to file when action is done, so a failed action
+// leaves file as it was. The results of interrupted scans are kept
+func writeFile(file string, action func(io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	actionErr := action(f)
+	if actionErr != nil && !errors.Is(actionErr, ErrInterrupted) {
+		f.Close()
+		return actionErr
+	}
+
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
 		return err
 	}
 
-	results := scan.Run(hl, ports)
-	return printResults(out, results)
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.Name(), file); err != nil {
+		return err
+	}
+
+	return actionErr
 }
 
 func printResults(out io.Writer, results []scan.Results) error {
 	message := ""
 	for _, r := range results {
-		message += fmt.Sprintf("%s:", r.Host)
+		message += fmt.Sprintf("%s:", hostHeader(r))
 		if r.NotFound {
 			message += " Host not found\n\n"
 			continue
 		}
 
+		if r.Down {
+			message += " Host down\n\n"
+			continue
+		}
+
 		message += fmt.Sprintln()
 		for _, p := range r.PortStates {
-			message += fmt.Sprintf("\t%d: %s\n", p.Port, p.Open)
+			port := fmt.Sprint(p.Port)
+			if p.Proto == scan.ProtoUDP {
+				port += "/" + p.Proto
+			}
+			message += fmt.Sprintf("\t%s: %s%s\n", port, p.State, portDetail(p))
+		}
+		for _, p := range r.PortStates {
+			if p.TLS != nil {
+				message += tlsDetail(p)
+			}
 		}
 		message += fmt.Sprintln()
 	}
@@ -67,3 +636,52 @@ func printResults(out io.Writer, results []scan.Results) error {
 	_, err := fmt.Fprint(out, message)
 	return err
 }
+
+// hostHeader returns the host of r with the addresses scanned, unless
+// the host is the only one, and the names they resolve back to
+func hostHeader(r scan.Results) string {
+	header := r.Host
+	addrs := scannedAddrs(r)
+	if len(addrs) > 1 || len(addrs) == 1 && addrs[0] != r.Host {
+		header += fmt.Sprintf(" (%s)", strings.Join(addrs, ", "))
+	}
+
+	if len(r.Names) > 0 {
+		header += fmt.Sprintf(" [%s]", strings.Join(r.Names, ", "))
+	}
+
+	return header
+}
+
+// tlsDetail returns the lines describing the TLS connection and
+// certificate of p, with what is wrong with the certificate
+func tlsDetail(p scan.PortState) string {
+	info := p.TLS
+	detail := fmt.Sprintf("\tTLS on port %d: %s, %s\n", p.Port, info.Version, info.Cipher)
+	detail += fmt.Sprintf("\t\tsubject: %s\n", info.Subject)
+	detail += fmt.Sprintf("\t\tissuer: %s\n", info.Issuer)
+	detail += fmt.Sprintf("\t\tSANs: %s\n", strings.Join(info.SANs, ", "))
+	detail += fmt.Sprintf("\t\texpires: %s\n", info.NotAfter.UTC().Format("2006-01-02 15:04:05 MST"))
+	for _, w := range tlsWarnings(info) {
+		detail += fmt.Sprintf("\t\twarning: %s\n", w)
+	}
+
+	return detail
+}
+
+// portDetail returns the latency of ports that answered followed by
+// the detected service, or the error behind StateError
+func portDetail(p scan.PortState) string {
+	switch p.State {
+	case scan.StateOpen, scan.StateClosed:
+		detail := fmt.Sprintf(" (%s)", p.Latency.Round(time.Microsecond))
+		if p.Service != "" {
+			detail += " " + strings.TrimSpace(p.Service+" "+p.Version)
+		}
+		return detail
+	case scan.StateError:
+		return fmt.Sprintf(" (%s)", p.Err)
+	}
+
+	return ""
+}
@@ -0,0 +1,154 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+	"github.com/spf13/cobra"
+)
+
+// setCmd represents the set command
+var setCmd = &cobra.Command{
+	Use:          "set <host1>...<host n>",
+	Short:        "Change the groups, labels, description or ports of host(s)",
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+	Long: `Change the groups, labels, description or ports of hosts in the list.
+
+Only the given flags change: --group replaces the groups of the hosts,
+--label adds or changes a label, or removes it when given without a
+value like env=, and --description and --ports replace the current
+ones. Pass an empty value to clear them.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile, err := cmd.Flags().GetString("hosts-file")
+		if err != nil {
+			return err
+		}
+
+		update, err := hostUpdate(cmd)
+		if err != nil {
+			return err
+		}
+
+		return setAction(os.Stdout, hostsFile, args, update)
+	},
+}
+
+func init() {
+	hostsCmd.AddCommand(setCmd)
+
+	addHostInfoFlags(setCmd)
+}
+
+// hostInfoFlag annotates the flags added by addHostInfoFlags, which the
+// config never sets: a profile's ports are ports to scan, not ports to
+// record for a host
+const hostInfoFlag = "pscan_host_info"
+
+// addHostInfoFlags adds the flags setting what the hosts list records
+// about a host
+func addHostInfoFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceP("group", "g", nil, "groups the hosts belong to")
+	cmd.Flags().StringSliceP("label", "l", nil, "labels as key=value")
+	cmd.Flags().StringP("description", "d", "", "description of the hosts")
+	cmd.Flags().StringP("ports", "p", "", "ports to scan on the hosts, e.g. 22,80-90,web")
+
+	for _, name := range []string{"group", "label", "description", "ports"} {
+		cmd.Flags().SetAnnotation(name, hostInfoFlag, []string{"true"})
+	}
+}
+
+// hostInfo returns the host information set with the flags
+func hostInfo(cmd *cobra.Command) (scan.HostInfo, error) {
+	info := scan.HostInfo{}
+	update, err := hostUpdate(cmd)
+	if err != nil {
+		return info, err
+	}
+
+	update(&info)
+	return info, nil
+}
+
+// hostUpdate returns a function applying the flags that were set to
+// the information about a host
+func hostUpdate(cmd *cobra.Command) (func(*scan.HostInfo), error) {
+	groups, err := cmd.Flags().GetStringSlice("group")
+	if err != nil {
+		return nil, err
+	}
+
+	labelArgs, err := cmd.Flags().GetStringSlice("label")
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := scan.ParseLabels(labelArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	description, err := cmd.Flags().GetString("description")
+	if err != nil {
+		return nil, err
+	}
+
+	ports, err := cmd.Flags().GetString("ports")
+	if err != nil {
+		return nil, err
+	}
+
+	if ports != "" {
+		if _, err := scan.ParsePorts(ports); err != nil {
+			return nil, err
+		}
+	}
+
+	changed := cmd.Flags().Changed
+	return func(info *scan.HostInfo) {
+		if changed("group") {
+			info.Groups = groups
+		}
+
+		for k, v := range labels {
+			if v == "" {
+				delete(info.Labels, k)
+				continue
+			}
+			if info.Labels == nil {
+				info.Labels = map[string]string{}
+			}
+			info.Labels[k] = v
+		}
+
+		if changed("description") {
+			info.Description = description
+		}
+
+		if changed("ports") {
+			info.Ports = ports
+		}
+	}, nil
+}
+
+func setAction(out io.Writer, hostsFile string, args []string, update func(*scan.HostInfo)) error {
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	for _, h := range args {
+		if err := hl.Update(h, update); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "Updated host:", h)
+	}
+
+	return hl.Save(hostsFile)
+}
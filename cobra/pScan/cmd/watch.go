@@ -0,0 +1,338 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bedminer1/cobra/pScan/history"
+	"github.com/bedminer1/cobra/pScan/scan"
+	"github.com/spf13/cobra"
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:          "watch",
+	Short:        "Scan the hosts on a schedule and report changes",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	Long: `Scan the hosts every --interval and report when a host appears or
+disappears, or a port changes state, like open to closed or filtered
+to closed, compared to the previous scan.
+
+Changes are written to stdout, and to --log-file and posted as JSON to
+--webhook when given. The first scan only sets the baseline. The hosts
+file is read again before each scan, so hosts can be added or removed
+while watching.
+
+With --listen, the results of the latest scan are served as JSON on
+that address, e.g. --listen localhost:9090.
+
+pScan watch stops on SIGINT or SIGTERM, cancelling the scan in
+progress. It takes the same flags as scan to choose the hosts and
+ports.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile, err := cmd.Flags().GetString("hosts-file")
+		if err != nil {
+			return err
+		}
+
+		sel, opts, err := scanSettings(cmd)
+		if err != nil {
+			return err
+		}
+
+		cfg := watchConfig{}
+		if cfg.interval, err = cmd.Flags().GetDuration("interval"); err != nil {
+			return err
+		}
+
+		if cfg.interval <= 0 {
+			return fmt.Errorf("interval must be positive, got %s", cfg.interval)
+		}
+
+		if cfg.logFile, err = cmd.Flags().GetString("log-file"); err != nil {
+			return err
+		}
+
+		if cfg.webhook, err = cmd.Flags().GetString("webhook"); err != nil {
+			return err
+		}
+
+		if cfg.listen, err = cmd.Flags().GetString("listen"); err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		return watchAction(ctx, os.Stdout, os.Stderr, hostsFile, sel, opts, cfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	addScanFlags(watchCmd)
+	watchCmd.Flags().Duration("interval", 5*time.Minute, "time between scans")
+	watchCmd.Flags().String("log-file", "", "also append changes to this file")
+	watchCmd.Flags().String("webhook", "", "also post changes as JSON to this URL")
+	watchCmd.Flags().String("listen", "", "serve the latest results on this address")
+}
+
+type watchConfig struct {
+	interval time.Duration
+	logFile  string
+	webhook  string
+	listen   string
+}
+
+// watchAction scans the hosts every cfg.interval until ctx is done and
+// reports the changes. Errors during a scan are written to errOut and
+// don't stop watching
+func watchAction(ctx context.Context, out, errOut io.Writer, hostsFile string, sel selection, opts scan.Options, cfg watchConfig) error {
+	w := &watcher{
+		interval:  cfg.interval,
+		notifiers: []notifier{textNotifier{out}},
+		state:     &watchState{},
+		errOut:    errOut,
+		scan: func(ctx context.Context) ([]scan.Results, error) {
+			_, results, err := runScan(ctx, hostsFile, sel, opts)
+			return results, err
+		},
+	}
+
+	if cfg.logFile != "" {
+		f, err := os.OpenFile(cfg.logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w.notifiers = append(w.notifiers, textNotifier{f})
+	}
+
+	if cfg.webhook != "" {
+		w.notifiers = append(w.notifiers, webhookNotifier{
+			url:    cfg.webhook,
+			client: &http.Client{Timeout: 10 * time.Second},
+		})
+	}
+
+	if cfg.listen != "" {
+		ln, err := net.Listen("tcp", cfg.listen)
+		if err != nil {
+			return err
+		}
+
+		srv := &http.Server{
+			Handler:      w.state,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+		go srv.Serve(ln)
+		defer srv.Close()
+
+		fmt.Fprintf(out, "Serving the latest results on http://%s/\n", ln.Addr())
+	}
+
+	fmt.Fprintf(out, "Watching the hosts in %s every %s\n", hostsFile, cfg.interval)
+	w.run(ctx)
+	_, err := fmt.Fprintln(out, "Stopped watching")
+	return err
+}
+
+// watcher scans on a schedule and notifies the changes between scans
+type watcher struct {
+	scan      func(ctx context.Context) ([]scan.Results, error)
+	interval  time.Duration
+	notifiers []notifier
+	state     *watchState
+	errOut    io.Writer
+}
+
+// run scans every interval until ctx is done
+func (w *watcher) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var last []scan.Results
+	for {
+		results, err := w.scan(ctx)
+		now := time.Now()
+		if ctx.Err() != nil {
+			// the scan was cut short
+			return
+		}
+
+		if err != nil {
+			fmt.Fprintln(w.errOut, "Error:", err)
+		} else {
+			if w.state.scans() > 0 {
+				w.notify(now, history.CompareStates(last, results))
+			}
+			last = results
+			w.state.set(now, results)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *watcher) notify(t time.Time, changes []history.Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	for _, n := range w.notifiers {
+		if err := n.notify(t, changes); err != nil {
+			fmt.Fprintln(w.errOut, "Error:", err)
+		}
+	}
+}
+
+// notifier sends the changes found by a scan somewhere
+type notifier interface {
+	notify(t time.Time, changes []history.Change) error
+}
+
+// textNotifier writes a line for each change
+type textNotifier struct {
+	w io.Writer
+}
+
+func (n textNotifier) notify(t time.Time, changes []history.Change) error {
+	for _, c := range changes {
+		if _, err := fmt.Fprintf(n.w, "%s %s\n", t.Format(time.RFC3339), c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// webhookNotifier posts the changes as JSON to url
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+type changeView struct {
+	Host     string `json:"host"`
+	Address  string `json:"address,omitempty"`
+	Change   string `json:"change"`
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Message  string `json:"message"`
+}
+
+type webhookPayload struct {
+	Time    time.Time    `json:"time"`
+	Changes []changeView `json:"changes"`
+}
+
+func (n webhookNotifier) notify(t time.Time, changes []history.Change) error {
+	payload := webhookPayload{Time: t}
+	for _, c := range changes {
+		payload.Changes = append(payload.Changes, changeView{
+			Host:     c.Host,
+			Address:  c.Addr,
+			Change:   c.Kind.String(),
+			Port:     c.Port,
+			Protocol: c.Proto,
+			From:     c.From,
+			To:       c.To,
+			Message:  c.String(),
+		})
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return err
+	}
+
+	r, err := n.client.Post(n.url, "application/json", &body)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return fmt.Errorf("webhook: %s returned %s", n.url, r.Status)
+	}
+
+	return nil
+}
+
+// watchState holds the latest results and serves them as JSON
+type watchState struct {
+	sync.RWMutex
+	updated time.Time
+	count   int
+	results []scan.Results
+}
+
+func (s *watchState) set(t time.Time, results []scan.Results) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.updated = t
+	s.count++
+	s.results = results
+}
+
+// scans returns the number of scans so far
+func (s *watchState) scans() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.count
+}
+
+type stateView struct {
+	Updated time.Time  `json:"updated"`
+	Scans   int        `json:"scans"`
+	Hosts   []hostView `json:"hosts"`
+}
+
+func (s *watchState) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.RLock()
+	v := stateView{
+		Updated: s.updated,
+		Scans:   s.count,
+		Hosts:   newHostViews(s.results),
+	}
+	s.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,165 @@
+package history
+
+import (
+	"fmt"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+)
+
+type changeKind int
+
+// kinds of changes between two runs
+const (
+	HostAppeared changeKind = iota
+	HostDisappeared
+	PortOpened
+	PortClosed
+	// PortChanged is a change between states other than open, only
+	// reported by CompareStates
+	PortChanged
+)
+
+func (k changeKind) String() string {
+	switch k {
+	case HostAppeared:
+		return "appeared"
+	case HostDisappeared:
+		return "disappeared"
+	case PortOpened:
+		return "opened"
+	case PortChanged:
+		return "changed"
+	}
+
+	return "closed"
+}
+
+// Change is a difference between the results of two runs
+type Change struct {
+	Kind changeKind
+	Host string
+	// Addr is the address of Host the change is on, for hosts scanned
+	// on a single address
+	Addr string
+	// Port, Proto, From and To are set for port changes, From and To
+	// being the states of the port in each run
+	Port  int
+	Proto string
+	From  string
+	To    string
+}
+
+func (c Change) String() string {
+	host := c.Host
+	if c.Addr != "" && c.Addr != c.Host {
+		host = fmt.Sprintf("%s (%s)", c.Host, c.Addr)
+	}
+
+	switch c.Kind {
+	case HostAppeared, HostDisappeared:
+		return fmt.Sprintf("%s %s", host, c.Kind)
+	}
+
+	return fmt.Sprintf("%s %d/%s %s (%s -> %s)", host, c.Port, c.Proto, c.Kind, c.From, c.To)
+}
+
+// Compare returns the changes from the results in from to those in
+// to: hosts found in only one of them, and ports scanned in both that
+// are open in only one. Changes follow the order of to, with the hosts
+// that disappeared last. Hosts scanned on each of their addresses are
+// compared address by address. Hosts down are like hosts not found
+func Compare(from, to []scan.Results) []Change {
+	return compare(from, to, false)
+}
+
+// CompareStates is like Compare, but reports any change in the state of
+// a port, like filtered to closed, and not only ports opening or closing
+func CompareStates(from, to []scan.Results) []Change {
+	return compare(from, to, true)
+}
+
+func compare(from, to []scan.Results, allStates bool) []Change {
+	type key struct{ host, addr string }
+
+	before := map[key]scan.Results{}
+	for _, r := range from {
+		before[key{r.Host, r.Addr}] = r
+	}
+
+	changes := []Change{}
+	seen := map[key]bool{}
+	for _, r := range to {
+		k := key{r.Host, r.Addr}
+		seen[k] = true
+		old, ok := before[k]
+		switch {
+		case gone(r) && (!ok || gone(old)):
+			continue
+		case gone(r):
+			changes = append(changes, Change{Kind: HostDisappeared, Host: r.Host, Addr: r.Addr})
+			continue
+		case !ok || gone(old):
+			changes = append(changes, Change{Kind: HostAppeared, Host: r.Host, Addr: r.Addr})
+			continue
+		}
+
+		changes = append(changes, comparePorts(r.Host, r.Addr, old.PortStates, r.PortStates, allStates)...)
+	}
+
+	for _, r := range from {
+		if !seen[key{r.Host, r.Addr}] && !gone(r) {
+			changes = append(changes, Change{Kind: HostDisappeared, Host: r.Host, Addr: r.Addr})
+		}
+	}
+
+	return changes
+}
+
+// gone reports whether r wasn't found or was down
+func gone(r scan.Results) bool {
+	return r.NotFound || r.Down
+}
+
+func comparePorts(host, addr string, from, to []scan.PortState, allStates bool) []Change {
+	type key struct {
+		port  int
+		proto string
+	}
+
+	before := map[key]scan.PortState{}
+	for _, p := range from {
+		before[key{p.Port, p.Proto}] = p
+	}
+
+	changes := []Change{}
+	for _, p := range to {
+		old, ok := before[key{p.Port, p.Proto}]
+		if !ok {
+			continue
+		}
+
+		wasOpen, isOpen := old.State == scan.StateOpen, p.State == scan.StateOpen
+		if old.State == p.State || (wasOpen == isOpen && !allStates) {
+			continue
+		}
+
+		c := Change{
+			Kind:  PortChanged,
+			Host:  host,
+			Addr:  addr,
+			Port:  p.Port,
+			Proto: p.Proto,
+			From:  old.State.String(),
+			To:    p.State.String(),
+		}
+		switch {
+		case isOpen:
+			c.Kind = PortOpened
+		case wasOpen:
+			c.Kind = PortClosed
+		}
+		changes = append(changes, c)
+	}
+
+	return changes
+}
@@ -0,0 +1,158 @@
+package history_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bedminer1/cobra/pScan/history"
+	"github.com/bedminer1/cobra/pScan/scan"
+)
+
+func ports(states ...scan.PortState) []scan.PortState {
+	return states
+}
+
+func port(p int, s string) scan.PortState {
+	st, err := scan.ParseState(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return scan.PortState{Port: p, Proto: scan.ProtoTCP, State: st}
+}
+
+func TestCompare(t *testing.T) {
+	testCases := []struct {
+		name   string
+		from   []scan.Results
+		to     []scan.Results
+		expect []history.Change
+	}{
+		{
+			name:   "NoChanges",
+			from:   []scan.Results{{Host: "host1", PortStates: ports(port(22, "open"), port(80, "closed"))}},
+			to:     []scan.Results{{Host: "host1", PortStates: ports(port(22, "open"), port(80, "filtered"))}},
+			expect: []history.Change{},
+		},
+		{
+			name: "Ports",
+			from: []scan.Results{{Host: "host1", PortStates: ports(port(22, "open"), port(80, "closed"))}},
+			to:   []scan.Results{{Host: "host1", PortStates: ports(port(22, "filtered"), port(80, "open"), port(443, "open"))}},
+			expect: []history.Change{
+				{Kind: history.PortClosed, Host: "host1", Port: 22, Proto: scan.ProtoTCP, From: "open", To: "filtered"},
+				{Kind: history.PortOpened, Host: "host1", Port: 80, Proto: scan.ProtoTCP, From: "closed", To: "open"},
+			},
+		},
+		{
+			name: "Hosts",
+			from: []scan.Results{
+				{Host: "host1"},
+				{Host: "host2"},
+				{Host: "host3", NotFound: true},
+				{Host: "host4"},
+			},
+			to: []scan.Results{
+				{Host: "host1", NotFound: true},
+				{Host: "host3"},
+				{Host: "host4"},
+				{Host: "host5"},
+			},
+			expect: []history.Change{
+				{Kind: history.HostDisappeared, Host: "host1"},
+				{Kind: history.HostAppeared, Host: "host3"},
+				{Kind: history.HostAppeared, Host: "host5"},
+				{Kind: history.HostDisappeared, Host: "host2"},
+			},
+		},
+		{
+			name: "Down",
+			from: []scan.Results{
+				{Host: "host1", PortStates: ports(port(22, "open"))},
+				{Host: "host2", Down: true},
+				{Host: "host3", Down: true},
+			},
+			to: []scan.Results{
+				{Host: "host1", Down: true},
+				{Host: "host2", PortStates: ports(port(22, "open"))},
+				{Host: "host3", NotFound: true},
+			},
+			expect: []history.Change{
+				{Kind: history.HostDisappeared, Host: "host1"},
+				{Kind: history.HostAppeared, Host: "host2"},
+			},
+		},
+		{
+			name: "Addresses",
+			from: []scan.Results{
+				{Host: "host1", Addr: "10.0.0.1", PortStates: ports(port(22, "open"))},
+				{Host: "host1", Addr: "10.0.0.2", PortStates: ports(port(22, "open"))},
+			},
+			to: []scan.Results{
+				{Host: "host1", Addr: "10.0.0.1", PortStates: ports(port(22, "closed"))},
+				{Host: "host1", Addr: "10.0.0.3", PortStates: ports(port(22, "open"))},
+			},
+			expect: []history.Change{
+				{Kind: history.PortClosed, Host: "host1", Addr: "10.0.0.1", Port: 22, Proto: scan.ProtoTCP, From: "open", To: "closed"},
+				{Kind: history.HostAppeared, Host: "host1", Addr: "10.0.0.3"},
+				{Kind: history.HostDisappeared, Host: "host1", Addr: "10.0.0.2"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			changes := history.Compare(tc.from, tc.to)
+			if !reflect.DeepEqual(changes, tc.expect) {
+				t.Errorf("Expected changes %v, got %v instead\n", tc.expect, changes)
+			}
+		})
+	}
+}
+
+func TestCompareStates(t *testing.T) {
+	from := []scan.Results{{Host: "host1", PortStates: ports(port(22, "open"), port(80, "filtered"), port(443, "closed"))}}
+	to := []scan.Results{{Host: "host1", PortStates: ports(port(22, "closed"), port(80, "closed"), port(443, "closed"))}}
+
+	expect := []history.Change{
+		{Kind: history.PortClosed, Host: "host1", Port: 22, Proto: scan.ProtoTCP, From: "open", To: "closed"},
+		{Kind: history.PortChanged, Host: "host1", Port: 80, Proto: scan.ProtoTCP, From: "filtered", To: "closed"},
+	}
+
+	if changes := history.CompareStates(from, to); !reflect.DeepEqual(changes, expect) {
+		t.Errorf("Expected changes %v, got %v instead\n", expect, changes)
+	}
+
+	// Compare only reports ports opening or closing
+	if changes := history.Compare(from, to); !reflect.DeepEqual(changes, expect[:1]) {
+		t.Errorf("Expected changes %v, got %v instead\n", expect[:1], changes)
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	testCases := []struct {
+		change history.Change
+		expect string
+	}{
+		{history.Change{Kind: history.HostAppeared, Host: "host1"}, "host1 appeared"},
+		{history.Change{Kind: history.HostDisappeared, Host: "host1"}, "host1 disappeared"},
+		{
+			history.Change{Kind: history.PortOpened, Host: "host1", Port: 53, Proto: scan.ProtoUDP, From: "open|filtered", To: "open"},
+			"host1 53/udp opened (open|filtered -> open)",
+		},
+		{
+			history.Change{Kind: history.PortClosed, Host: "host1", Addr: "10.0.0.1", Port: 22, Proto: scan.ProtoTCP, From: "open", To: "closed"},
+			"host1 (10.0.0.1) 22/tcp closed (open -> closed)",
+		},
+		{
+			history.Change{Kind: history.PortChanged, Host: "host1", Port: 80, Proto: scan.ProtoTCP, From: "filtered", To: "closed"},
+			"host1 80/tcp changed (filtered -> closed)",
+		},
+		{history.Change{Kind: history.HostAppeared, Host: "10.0.0.1", Addr: "10.0.0.1"}, "10.0.0.1 appeared"},
+	}
+
+	for _, tc := range testCases {
+		if s := tc.change.String(); s != tc.expect {
+			t.Errorf("Expected %q, got %q instead\n", tc.expect, s)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package history keeps the results of past scans in a SQLite database
+package history
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var ErrNotFound = errors.New("run not found")
+
+const createTables string = `CREATE TABLE IF NOT EXISTS "runs" (
+"id" INTEGER,
+"start_time" DATETIME NOT NULL,
+"end_time" DATETIME NOT NULL,
+"args" TEXT DEFAULT '',
+PRIMARY KEY("id")
+);
+CREATE TABLE IF NOT EXISTS "hosts" (
+"run_id" INTEGER NOT NULL,
+"host" TEXT NOT NULL,
+"addresses" TEXT DEFAULT '',
+"not_found" INTEGER DEFAULT 0,
+"address" TEXT DEFAULT '',
+"names" TEXT DEFAULT '',
+"down" INTEGER DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS "ports" (
+"run_id" INTEGER NOT NULL,
+"host" TEXT NOT NULL,
+"port" INTEGER NOT NULL,
+"protocol" TEXT NOT NULL,
+"state" TEXT NOT NULL,
+"latency" INTEGER DEFAULT 0,
+"service" TEXT DEFAULT '',
+"version" TEXT DEFAULT '',
+"error" TEXT DEFAULT '',
+"address" TEXT DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS "hosts_run" ON "hosts" ("run_id");
+CREATE INDEX IF NOT EXISTS "ports_run" ON "ports" ("run_id");`
+
+// Run is a scan and its results
+type Run struct {
+	ID    int64
+	Start time.Time
+	End   time.Time
+	// Args is the command line that started the scan
+	Args string
+	// Hosts and Open are the number of hosts scanned and of open ports
+	// found, set by Runs
+	Hosts int
+	Open  int
+	// Results are set by Get
+	Results []scan.Results
+}
+
+// Store saves runs in a SQLite database
+type Store struct {
+	db *sql.DB
+	sync.RWMutex
+}
+
+// Open opens the database in dbfile, creating it if needed
+func Open(dbfile string) (*Store, error) {
+	db, err := sql.Open("sqlite3", dbfile)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetConnMaxLifetime(30 * time.Minute)
+	db.SetMaxOpenConns(1)
+
+	// verify connection established
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if _, err := db.Exec(createTables); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save stores r with its results and returns its ID
+func (s *Store) Save(r Run) (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO runs VALUES(NULL, ?,?,?)", r.Start, r.End, r.Args)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	hostStmt, err := tx.Prepare("INSERT INTO hosts VALUES(?,?,?,?,?,?,?)")
+	if err != nil {
+		return 0, err
+	}
+	defer hostStmt.Close()
+
+	portStmt, err := tx.Prepare("INSERT INTO ports VALUES(?,?,?,?,?,?,?,?,?,?)")
+	if err != nil {
+		return 0, err
+	}
+	defer portStmt.Close()
+
+	for _, h := range r.Results {
+		if _, err := hostStmt.Exec(id, h.Host, strings.Join(h.Addrs, ","), h.NotFound,
+			h.Addr, strings.Join(h.Names, ","), h.Down); err != nil {
+			return 0, err
+		}
+
+		for _, p := range h.PortStates {
+			errMsg := ""
+			if p.Err != nil {
+				errMsg = p.Err.Error()
+			}
+
+			_, err := portStmt.Exec(id, h.Host, p.Port, p.Proto, p.State.String(),
+				p.Latency, p.Service, p.Version, errMsg, h.Addr)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Runs returns the last limit runs, newest first, without their
+// results. A limit of 0 returns all runs
+func (s *Store) Runs(limit int) ([]Run, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := s.db.Query(`SELECT r.id, r.start_time, r.end_time, r.args,
+(SELECT COUNT(*) FROM hosts h WHERE h.run_id = r.id),
+(SELECT COUNT(*) FROM ports p WHERE p.run_id = r.id AND p.state = ?)
+FROM runs r ORDER BY r.id DESC LIMIT ?`, scan.StateOpen.String(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []Run{}
+	for rows.Next() {
+		r := Run{}
+		if err := rows.Scan(&r.ID, &r.Start, &r.End, &r.Args, &r.Hosts, &r.Open); err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+
+	return runs, rows.Err()
+}
+
+// Get returns the run with id and its results
+func (s *Store) Get(id int64) (Run, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	r := Run{}
+	row := s.db.QueryRow("SELECT id, start_time, end_time, args FROM runs WHERE id = ?", id)
+	if err := row.Scan(&r.ID, &r.Start, &r.End, &r.Args); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return r, fmt.Errorf("%w: %d", ErrNotFound, id)
+		}
+		return r, err
+	}
+
+	hosts, err := s.db.Query(`SELECT host, addresses, not_found, address, names, down
+FROM hosts WHERE run_id = ? ORDER BY rowid`, id)
+	if err != nil {
+		return r, err
+	}
+	defer hosts.Close()
+
+	// hosts scanned on every address are there once per address
+	type key struct{ host, addr string }
+	index := map[key]int{}
+	for hosts.Next() {
+		h := scan.Results{}
+		var addrs, names string
+		if err := hosts.Scan(&h.Host, &addrs, &h.NotFound, &h.Addr, &names, &h.Down); err != nil {
+			return r, err
+		}
+		if addrs != "" {
+			h.Addrs = strings.Split(addrs, ",")
+		}
+		if names != "" {
+			h.Names = strings.Split(names, ",")
+		}
+
+		index[key{h.Host, h.Addr}] = len(r.Results)
+		r.Results = append(r.Results, h)
+	}
+	if err := hosts.Err(); err != nil {
+		return r, err
+	}
+
+	ports, err := s.db.Query(`SELECT host, address, port, protocol, state, latency, service, version, error
+FROM ports WHERE run_id = ? ORDER BY rowid`, id)
+	if err != nil {
+		return r, err
+	}
+	defer ports.Close()
+
+	for ports.Next() {
+		var host, addr, state, errMsg string
+		p := scan.PortState{}
+		if err := ports.Scan(&host, &addr, &p.Port, &p.Proto, &state, &p.Latency, &p.Service, &p.Version, &errMsg); err != nil {
+			return r, err
+		}
+
+		if p.State, err = scan.ParseState(state); err != nil {
+			return r, err
+		}
+		if errMsg != "" {
+			p.Err = errors.New(errMsg)
+		}
+
+		i, ok := index[key{host, addr}]
+		if !ok {
+			continue
+		}
+		r.Results[i].PortStates = append(r.Results[i].PortStates, p)
+	}
+
+	return r, ports.Err()
+}
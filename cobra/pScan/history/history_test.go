@@ -0,0 +1,143 @@
+package history_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bedminer1/cobra/pScan/history"
+	"github.com/bedminer1/cobra/pScan/scan"
+)
+
+func getStore(t *testing.T) *history.Store {
+	t.Helper()
+
+	s, err := history.Open(filepath.Join(t.TempDir(), "pScan.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestSaveGet(t *testing.T) {
+	s := getStore(t)
+
+	start := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+	run := history.Run{
+		Start: start,
+		End:   start.Add(2 * time.Second),
+		Args:  "pScan scan -p 22,80",
+		Results: []scan.Results{
+			{
+				Host:  "host1",
+				Addrs: []string{"10.0.0.1", "10.0.0.2"},
+				PortStates: []scan.PortState{
+					{Port: 22, Proto: scan.ProtoTCP, State: scan.StateOpen, Latency: time.Millisecond, Service: "ssh", Version: "OpenSSH_9.6"},
+					{Port: 80, Proto: scan.ProtoTCP, State: scan.StateError, Err: errors.New("too many open files")},
+				},
+			},
+			{Host: "host2", NotFound: true},
+			{Host: "host4", Addrs: []string{"10.0.0.5"}, Down: true},
+			{
+				Host:       "host3",
+				Addrs:      []string{"10.0.0.3", "10.0.0.4"},
+				Addr:       "10.0.0.3",
+				Names:      []string{"host3.example.com"},
+				PortStates: []scan.PortState{{Port: 22, Proto: scan.ProtoTCP, State: scan.StateOpen, Latency: time.Millisecond}},
+			},
+			{
+				Host:       "host3",
+				Addrs:      []string{"10.0.0.3", "10.0.0.4"},
+				Addr:       "10.0.0.4",
+				PortStates: []scan.PortState{{Port: 22, Proto: scan.ProtoTCP, State: scan.StateClosed, Latency: time.Millisecond}},
+			},
+		},
+	}
+
+	id, err := s.Save(run)
+	if err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	got, err := s.Get(id)
+	if err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	run.ID = id
+	if !got.Start.Equal(run.Start) || !got.End.Equal(run.End) {
+		t.Errorf("Expected run from %s to %s, got %s to %s instead\n", run.Start, run.End, got.Start, got.End)
+	}
+	got.Start, got.End = run.Start, run.End
+
+	if !reflect.DeepEqual(got, run) {
+		t.Errorf("Expected run %+v, got %+v instead\n", run, got)
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	s := getStore(t)
+
+	if _, err := s.Get(1); !errors.Is(err, history.ErrNotFound) {
+		t.Errorf("Expected error %q, got %q instead\n", history.ErrNotFound, err)
+	}
+}
+
+func TestRuns(t *testing.T) {
+	s := getStore(t)
+
+	results := []scan.Results{
+		{
+			Host: "host1",
+			PortStates: []scan.PortState{
+				{Port: 22, Proto: scan.ProtoTCP, State: scan.StateOpen},
+				{Port: 80, Proto: scan.ProtoTCP, State: scan.StateOpen},
+				{Port: 443, Proto: scan.ProtoTCP, State: scan.StateClosed},
+			},
+		},
+		{Host: "host2", NotFound: true},
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		r := history.Run{Start: start, End: start, Results: results[:i%2+1]}
+		if _, err := s.Save(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs, err := s.Runs(2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if len(runs) != 2 {
+		t.Fatalf("Expected 2 runs, got %d instead\n", len(runs))
+	}
+
+	// newest first
+	if runs[0].ID != 3 || runs[1].ID != 2 {
+		t.Errorf("Expected runs 3 and 2, got %d and %d instead\n", runs[0].ID, runs[1].ID)
+	}
+
+	if runs[0].Hosts != 1 || runs[0].Open != 2 {
+		t.Errorf("Expected 1 host and 2 open ports, got %d and %d instead\n", runs[0].Hosts, runs[0].Open)
+	}
+
+	if runs[1].Hosts != 2 {
+		t.Errorf("Expected 2 hosts, got %d instead\n", runs[1].Hosts)
+	}
+
+	all, err := s.Runs(0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if len(all) != 3 {
+		t.Errorf("Expected 3 runs, got %d instead\n", len(all))
+	}
+}
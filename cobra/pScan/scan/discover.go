@@ -0,0 +1,360 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrICMP = errors.New("ICMP echo not available")
+
+// DefaultPingPorts are the ports the TCP ping tries when
+// Options.PingPorts isn't set, ports most hosts either serve or refuse
+var DefaultPingPorts = []int{80, 443, 22, 445, 3389}
+
+// HostStatus tells whether a host answered discovery
+type HostStatus struct {
+	Host string
+	// Addr is the address pinged, empty when Host wasn't found
+	Addr     string
+	NotFound bool
+	Up       bool
+	// Reason is what answered: "tcp/N" for a port that accepted or
+	// refused the connection, "icmp" for an echo reply
+	Reason  string
+	Latency time.Duration
+}
+
+func (o Options) pingPorts() []int {
+	if len(o.PingPorts) == 0 {
+		return DefaultPingPorts
+	}
+
+	return o.PingPorts
+}
+
+// Discover finds out which of hosts are up, with a TCP ping to the
+// ports in opts.PingPorts and, with opts.ICMP, an ICMP echo. A host is
+// up if any port accepts or refuses the connection, or the echo is
+// answered. Like RunContext, it stops when ctx is done and returns the
+// hosts checked so far with the error of ctx
+func Discover(ctx context.Context, hosts []string, opts Options) ([]HostStatus, error) {
+	opts = opts.withDefaults()
+
+	resolver, err := newResolver(opts.Resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	var pinger *icmpPinger
+	if opts.ICMP {
+		if pinger, err = newICMPPinger(); err != nil {
+			return nil, err
+		}
+		defer pinger.close()
+	}
+
+	l := newLimiter(opts.Rate)
+	defer l.stop()
+
+	status := make([]*HostStatus, len(hosts))
+	pool(ctx, len(hosts), opts.Workers, func(i int) {
+		if !l.wait(ctx) {
+			return
+		}
+
+		s := HostStatus{Host: hosts[i]}
+		lk := resolve(ctx, resolver, hosts[i], opts)
+		if lk.err != nil {
+			s.NotFound = true
+		} else {
+			s.Addr = lk.addrs[0]
+			s.Reason, s.Latency, s.Up = ping(ctx, s.Addr, s.Addr, opts, pinger)
+		}
+
+		if !stopped(ctx) {
+			status[i] = &s
+		}
+	})
+
+	res := []HostStatus{}
+	for _, s := range status {
+		if s != nil {
+			res = append(res, *s)
+		}
+	}
+
+	if stopped(ctx) {
+		err := ctx.Err()
+		if err == nil {
+			err = context.DeadlineExceeded
+		}
+		return res, err
+	}
+
+	return res, nil
+}
+
+// skipDown pings the hosts in res that have ports to scan, connecting
+// to dial, and marks those that don't answer as down, with no ports
+func skipDown(ctx context.Context, res []Results, dial []string, ports [][]int, opts Options) error {
+	var pinger *icmpPinger
+	if opts.ICMP {
+		var err error
+		if pinger, err = newICMPPinger(); err != nil {
+			return err
+		}
+		defer pinger.close()
+	}
+
+	l := newLimiter(opts.Rate)
+	defer l.stop()
+
+	pool(ctx, len(res), opts.Workers, func(h int) {
+		if len(ports[h]) == 0 || !l.wait(ctx) {
+			return
+		}
+
+		addr := res[h].Addr
+		if addr == "" && len(res[h].Addrs) > 0 {
+			addr = res[h].Addrs[0]
+		}
+
+		if _, _, up := ping(ctx, dial[h], addr, opts, pinger); up || stopped(ctx) {
+			return
+		}
+
+		res[h].Down = true
+		res[h].PortStates = nil
+		ports[h] = nil
+	})
+
+	return nil
+}
+
+// ping checks whether the host at dial answers the TCP ping or, with
+// pinger, the ICMP echo sent to addr, and returns what answered first
+func ping(ctx context.Context, dial, addr string, opts Options, pinger *icmpPinger) (string, time.Duration, bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type answer struct {
+		reason  string
+		latency time.Duration
+		up      bool
+	}
+
+	answers := make(chan answer, 2)
+	go func() {
+		port, latency, up := tcpPing(ctx, dial, opts.pingPorts(), opts.Timeout)
+		answers <- answer{fmt.Sprintf("%s/%d", ProtoTCP, port), latency, up}
+	}()
+
+	n := 1
+	if pinger != nil {
+		n++
+		go func() {
+			latency, up := pinger.ping(ctx, addr, opts.Timeout)
+			answers <- answer{"icmp", latency, up}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		if a := <-answers; a.up {
+			return a.reason, a.latency, true
+		}
+	}
+
+	return "", 0, false
+}
+
+// tcpPing connects to ports on addr at the same time and returns the
+// first port that accepted or refused the connection
+func tcpPing(ctx context.Context, addr string, ports []int, timeout time.Duration) (int, time.Duration, bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type answer struct {
+		port    int
+		latency time.Duration
+	}
+
+	answers := make(chan answer, len(ports))
+	var wg sync.WaitGroup
+	for _, port := range ports {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+
+			start := time.Now()
+			conn, err := dialContext(ctx, "tcp", net.JoinHostPort(addr, fmt.Sprint(port)), timeout)
+			if err == nil {
+				conn.Close()
+			}
+
+			// a refused connection comes from the host too
+			if err == nil || classify(err) == StateClosed {
+				answers <- answer{port, time.Since(start)}
+			}
+		}(port)
+	}
+
+	go func() {
+		wg.Wait()
+		close(answers)
+	}()
+
+	a, ok := <-answers
+	return a.port, a.latency, ok
+}
+
+// icmpPinger sends ICMP echo requests to IPv4 addresses over a raw
+// socket, which needs root or CAP_NET_RAW
+type icmpPinger struct {
+	conn net.PacketConn
+	id   int
+
+	mu  sync.Mutex
+	seq int
+	// waiting holds the pings waiting for a reply, by sequence number,
+	// so pings to the same address don't take each other's replies
+	waiting map[int]echoWaiter
+}
+
+// echoWaiter is a ping waiting for the reply from addr
+type echoWaiter struct {
+	addr  string
+	reply chan struct{}
+}
+
+func newICMPPinger() (*icmpPinger, error) {
+	conn, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return nil, fmt.Errorf("%w, it needs root privileges: %w", ErrICMP, err)
+	}
+
+	p := &icmpPinger{
+		conn:    conn,
+		id:      os.Getpid() & 0xffff,
+		waiting: map[int]echoWaiter{},
+	}
+	go p.read()
+
+	return p, nil
+}
+
+func (p *icmpPinger) close() error {
+	return p.conn.Close()
+}
+
+// read hands the echo replies to our requests to the pings waiting for
+// them, until the socket is closed. Raw sockets get every ICMP message
+// the host receives
+func (p *icmpPinger) read() {
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := p.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		msg := buf[:n]
+		if len(msg) < 8 || msg[0] != icmpEchoReply || int(binary.BigEndian.Uint16(msg[4:])) != p.id {
+			continue
+		}
+
+		ipAddr, ok := from.(*net.IPAddr)
+		if !ok {
+			continue
+		}
+
+		seq := int(binary.BigEndian.Uint16(msg[6:]))
+		p.mu.Lock()
+		if w, ok := p.waiting[seq]; ok && w.addr == ipAddr.IP.String() {
+			select {
+			case w.reply <- struct{}{}:
+			default:
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// ping sends an echo request to addr and waits up to timeout for the
+// reply. IPv6 addresses aren't pinged
+func (p *icmpPinger) ping(ctx context.Context, addr string, timeout time.Duration) (time.Duration, bool) {
+	ip := net.ParseIP(addr).To4()
+	if ip == nil {
+		return 0, false
+	}
+
+	reply := make(chan struct{}, 1)
+	p.mu.Lock()
+	p.seq = (p.seq + 1) & 0xffff
+	seq := p.seq
+	p.waiting[seq] = echoWaiter{addr: ip.String(), reply: reply}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.waiting, seq)
+		p.mu.Unlock()
+	}()
+
+	start := time.Now()
+	if _, err := p.conn.WriteTo(echoRequest(p.id, seq), &net.IPAddr{IP: ip}); err != nil {
+		return 0, false
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-reply:
+		return time.Since(start), true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	return 0, false
+}
+
+// ICMP message types
+const (
+	icmpEchoReply   = 0
+	icmpEchoRequest = 8
+)
+
+// echoRequest returns an ICMP echo request with id and seq
+func echoRequest(id, seq int) []byte {
+	msg := make([]byte, 8, 16)
+	msg[0] = icmpEchoRequest
+	binary.BigEndian.PutUint16(msg[4:], uint16(id))
+	binary.BigEndian.PutUint16(msg[6:], uint16(seq))
+	msg = append(msg, "pScan..."...)
+	binary.BigEndian.PutUint16(msg[2:], checksum(msg))
+
+	return msg
+}
+
+// checksum is the internet checksum of b, RFC 1071
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+
+	return ^uint16(sum)
+}
@@ -0,0 +1,158 @@
+package scan_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+)
+
+// pingPorts returns a port accepting connections and a closed one on
+// localhost
+func pingPorts(t *testing.T) (int, int) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	return ln.Addr().(*net.TCPAddr).Port, closedPort
+}
+
+func TestDiscover(t *testing.T) {
+	open, closed := pingPorts(t)
+	defer scan.SetUnreachable("127.0.0.2")()
+
+	testCases := []struct {
+		name         string
+		host         string
+		pingPorts    []int
+		expectUp     bool
+		expectReason string
+		expectNF     bool
+	}{
+		{"Open", "127.0.0.1", []int{open}, true, fmt.Sprintf("tcp/%d", open), false},
+		{"Refused", "127.0.0.1", []int{closed}, true, fmt.Sprintf("tcp/%d", closed), false},
+		{"Down", "127.0.0.2", []int{open, closed}, false, "", false},
+		{"NotFound", "389.389.389.389", []int{open}, false, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := scan.Options{PingPorts: tc.pingPorts, Timeout: 200 * time.Millisecond}
+			status, err := scan.Discover(context.Background(), []string{tc.host}, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(status) != 1 {
+				t.Fatalf("Expected 1 host, got %d instead\n", len(status))
+			}
+
+			s := status[0]
+			if s.Host != tc.host || s.Up != tc.expectUp || s.Reason != tc.expectReason || s.NotFound != tc.expectNF {
+				t.Errorf("Expected %s up %t with reason %q and not found %t, got %+v instead\n",
+					tc.host, tc.expectUp, tc.expectReason, tc.expectNF, s)
+			}
+		})
+	}
+}
+
+func TestDiscoverOrder(t *testing.T) {
+	_, closed := pingPorts(t)
+	defer scan.SetUnreachable("127.0.0.3")()
+
+	hosts := []string{"127.0.0.1", "127.0.0.2", "127.0.0.3", "127.0.0.4"}
+	opts := scan.Options{PingPorts: []int{closed}, Timeout: 200 * time.Millisecond, Workers: 4}
+	status, err := scan.Discover(context.Background(), hosts, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	up := []string{}
+	for i, s := range status {
+		if s.Host != hosts[i] {
+			t.Errorf("Expected host %d to be %s, got %s instead\n", i, hosts[i], s.Host)
+		}
+		if s.Up {
+			up = append(up, s.Host)
+		}
+	}
+
+	if got := strings.Join(up, ","); got != "127.0.0.1,127.0.0.2,127.0.0.4" {
+		t.Errorf("Expected hosts 1, 2 and 4 up, got %s instead\n", got)
+	}
+}
+
+func TestDiscoverCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	status, err := scan.Discover(ctx, []string{"127.0.0.1"}, scan.Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error %q, got %v instead\n", context.Canceled, err)
+	}
+
+	if len(status) != 0 {
+		t.Errorf("Expected no hosts, got %v instead\n", status)
+	}
+}
+
+func TestRunSkipDown(t *testing.T) {
+	open, closed := pingPorts(t)
+	defer scan.SetUnreachable("127.0.0.2")()
+
+	targets := []scan.Target{
+		{Host: "127.0.0.1", Ports: []int{open}},
+		{Host: "127.0.0.2", Ports: []int{open}},
+	}
+
+	opts := scan.Options{SkipDown: true, PingPorts: []int{closed}, Timeout: 200 * time.Millisecond}
+	res := scan.RunTargets(targets, opts)
+
+	if len(res) != 2 {
+		t.Fatalf("Expected 2 results, got %d instead\n", len(res))
+	}
+
+	if res[0].Down || len(res[0].PortStates) != 1 || res[0].PortStates[0].State != scan.StateOpen {
+		t.Errorf("Expected 127.0.0.1 up with port %d open, got %+v instead\n", open, res[0])
+	}
+
+	if !res[1].Down || len(res[1].PortStates) != 0 {
+		t.Errorf("Expected 127.0.0.2 down without ports, got %+v instead\n", res[1])
+	}
+
+	res = scan.RunTargets(targets, scan.Options{Timeout: 200 * time.Millisecond})
+	if res[1].Down || len(res[1].PortStates) != 1 {
+		t.Errorf("Expected 127.0.0.2 scanned without SkipDown, got %+v instead\n", res[1])
+	}
+}
+
+func TestPingICMP(t *testing.T) {
+	// pings to the same address at the same time each get their reply
+	up, err := scan.PingICMP("127.0.0.1", 5, time.Second)
+	if errors.Is(err, scan.ErrICMP) {
+		t.Skip("ICMP echo needs root privileges:", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if up != 5 {
+		t.Errorf("Expected 5 echo replies from 127.0.0.1, got %d instead\n", up)
+	}
+}
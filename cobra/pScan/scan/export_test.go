@@ -0,0 +1,71 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+)
+
+// SetDialDelay makes every connection wait d before dialing and returns
+// a function restoring the real dial
+func SetDialDelay(d time.Duration) func() {
+	orig := dialContext
+	dialContext = func(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return orig(ctx, network, address, timeout)
+	}
+
+	return func() { dialContext = orig }
+}
+
+var UDPProbe = udpProbe
+var Classify = classify
+
+// SetUnreachable makes the connections to addrs time out at once and
+// returns a function restoring the real dial
+func SetUnreachable(addrs ...string) func() {
+	orig := dialContext
+	dialContext = func(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+		host, _, _ := net.SplitHostPort(address)
+		for _, a := range addrs {
+			if host == a {
+				return nil, &net.OpError{Op: "dial", Net: network, Err: os.ErrDeadlineExceeded}
+			}
+		}
+		return orig(ctx, network, address, timeout)
+	}
+
+	return func() { dialContext = orig }
+}
+
+// PingICMP sends n ICMP echoes to addr at the same time and returns
+// how many were answered within timeout
+func PingICMP(addr string, n int, timeout time.Duration) (int, error) {
+	p, err := newICMPPinger()
+	if err != nil {
+		return 0, err
+	}
+	defer p.close()
+
+	answered := make(chan bool, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, ok := p.ping(context.Background(), addr, timeout)
+			answered <- ok
+		}()
+	}
+
+	up := 0
+	for i := 0; i < n; i++ {
+		if <-answered {
+			up++
+		}
+	}
+
+	return up, nil
+}
@@ -2,19 +2,72 @@ package scan
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 )
 
 var (
-	ErrExists = errors.New("host already in the list")
-	ErrNotExists = errors.New("host not in the list")
+	ErrExists           = errors.New("host already in the list")
+	ErrNotExists        = errors.New("host not in the list")
+	ErrInvalidHostsFile = errors.New("invalid hosts file")
+	ErrInvalidLabel     = errors.New("invalid label")
 )
 
+// HostInfo is what the hosts list records about an entry
+type HostInfo struct {
+	Groups      []string          `json:"groups,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Description string            `json:"description,omitempty"`
+	// Ports is a port spec, as taken by ParsePorts, scanned on the
+	// entry instead of the default ports
+	Ports string `json:"ports,omitempty"`
+}
+
+func (i HostInfo) empty() bool {
+	return len(i.Groups) == 0 && len(i.Labels) == 0 && i.Description == "" && i.Ports == ""
+}
+
+// InGroup reports whether the entry belongs to group
+func (i HostInfo) InGroup(group string) bool {
+	for _, g := range i.Groups {
+		if g == group {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Group is a set of entries sharing a description and default ports
+type Group struct {
+	Description string `json:"description,omitempty"`
+	Ports       string `json:"ports,omitempty"`
+}
+
 type HostsList struct {
 	Hosts []string
+	// Info holds the groups, labels, description and ports of the
+	// entries that have any
+	Info map[string]HostInfo
+	// Groups holds the groups with a description or default ports,
+	// entries can belong to groups not listed here
+	Groups map[string]Group
+}
+
+// hostsFileData is the layout of the hosts file
+type hostsFileData struct {
+	Groups map[string]Group `json:"groups,omitempty"`
+	Hosts  []hostEntry      `json:"hosts"`
+}
+
+type hostEntry struct {
+	Host string `json:"host"`
+	HostInfo
 }
 
 // search for host in list
@@ -28,7 +81,14 @@ func (hl *HostsList) search(host string) (bool, int) {
 	return false, -1
 }
 
+// Add adds host to the list. Besides host names and addresses, host
+// can be a CIDR block (10.0.0.0/24), an address range (192.168.1.10-50
+// or 2001:db8::1-2001:db8::ff) or a name pattern (web[01-10].internal)
 func (hl *HostsList) Add(host string) error {
+	if _, err := parseTarget(host); err != nil {
+		return err
+	}
+
 	if found, _ := hl.search(host); found {
 		return fmt.Errorf("%w: %s", ErrExists, host)
 	}
@@ -40,37 +100,297 @@ func (hl *HostsList) Add(host string) error {
 func (hl *HostsList) Remove(host string) error {
 	if found, i := hl.search(host); found {
 		hl.Hosts = append(hl.Hosts[:i], hl.Hosts[i+1:]...)
+		delete(hl.Info, host)
 		return nil
 	}
 
 	return fmt.Errorf("%w: %s", ErrNotExists, host)
 }
 
-// read from hostsFile into hl.Hosts
+// Update changes the information about host with fn
+func (hl *HostsList) Update(host string, fn func(info *HostInfo)) error {
+	if found, _ := hl.search(host); !found {
+		return fmt.Errorf("%w: %s", ErrNotExists, host)
+	}
+
+	info := hl.Info[host]
+	fn(&info)
+	hl.setInfo(host, info)
+	return nil
+}
+
+func (hl *HostsList) setInfo(host string, info HostInfo) {
+	if info.empty() {
+		delete(hl.Info, host)
+		return
+	}
+
+	if hl.Info == nil {
+		hl.Info = map[string]HostInfo{}
+	}
+	hl.Info[host] = info
+}
+
+// SetGroup adds or changes group
+func (hl *HostsList) SetGroup(name string, g Group) {
+	if hl.Groups == nil {
+		hl.Groups = map[string]Group{}
+	}
+	hl.Groups[name] = g
+}
+
+// RemoveGroup deletes group and takes its entries out of it
+func (hl *HostsList) RemoveGroup(name string) error {
+	_, defined := hl.Groups[name]
+	members := false
+	for h, info := range hl.Info {
+		if !info.InGroup(name) {
+			continue
+		}
+
+		members = true
+		groups := []string{}
+		for _, g := range info.Groups {
+			if g != name {
+				groups = append(groups, g)
+			}
+		}
+		info.Groups = groups
+		hl.setInfo(h, info)
+	}
+
+	if !defined && !members {
+		return fmt.Errorf("%w: group %s", ErrNotExists, name)
+	}
+
+	delete(hl.Groups, name)
+	return nil
+}
+
+// ParseLabels parses labels given as key=value
+func ParseLabels(labels []string) (map[string]string, error) {
+	res := map[string]string{}
+	for _, l := range labels {
+		k, v, ok := strings.Cut(l, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("%w: %q, use key=value", ErrInvalidLabel, l)
+		}
+		res[k] = strings.TrimSpace(v)
+	}
+
+	return res, nil
+}
+
+// Filter returns the entries that belong to any of groups and have all
+// labels. No groups or labels match every entry
+func (hl *HostsList) Filter(groups []string, labels map[string]string) *HostsList {
+	res := &HostsList{Groups: hl.Groups}
+	for _, h := range hl.Hosts {
+		info := hl.Info[h]
+		if !matches(info, groups, labels) {
+			continue
+		}
+
+		res.Hosts = append(res.Hosts, h)
+		if !info.empty() {
+			res.setInfo(h, info)
+		}
+	}
+
+	return res
+}
+
+func matches(info HostInfo, groups []string, labels map[string]string) bool {
+	inGroup := len(groups) == 0
+	for _, g := range groups {
+		if info.InGroup(g) {
+			inGroup = true
+			break
+		}
+	}
+	if !inGroup {
+		return false
+	}
+
+	for k, v := range labels {
+		if l, ok := info.Labels[k]; !ok || l != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PortSpec returns the ports set for entry, its own or those of the
+// first of its groups that has them, or "" if there are none
+func (hl *HostsList) PortSpec(entry string) string {
+	info := hl.Info[entry]
+	if info.Ports != "" {
+		return info.Ports
+	}
+
+	for _, g := range info.Groups {
+		if p := hl.Groups[g].Ports; p != "" {
+			return p
+		}
+	}
+
+	return ""
+}
+
+// Load reads the hosts list from hostsFile. Files in the old format,
+// one host per line, are read too and saved in the new one the next
+// time the list is saved
 func (hl *HostsList) Load(hostsFile string) error {
-	f, err := os.Open(hostsFile)
+	data, err := os.ReadFile(hostsFile)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
 		return err
 	}
-	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		hl.Hosts = append(hl.Hosts, scanner.Text())
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil
+	}
+
+	if trimmed[0] != '{' {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			if host := strings.TrimSpace(scanner.Text()); host != "" {
+				hl.Hosts = append(hl.Hosts, host)
+			}
+		}
+		return scanner.Err()
+	}
+
+	var f hostsFileData
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidHostsFile, hostsFile, err)
+	}
+
+	hl.Groups = f.Groups
+	for _, e := range f.Hosts {
+		hl.Hosts = append(hl.Hosts, e.Host)
+		if !e.HostInfo.empty() {
+			hl.setInfo(e.Host, e.HostInfo)
+		}
 	}
 
 	return nil
 }
 
-// write to hostsFile
+// Save writes the hosts list to hostsFile as JSON
 func (hl *HostsList) Save(hostsFile string) error {
-	output := ""
+	f := hostsFileData{
+		Groups: hl.Groups,
+		Hosts:  make([]hostEntry, 0, len(hl.Hosts)),
+	}
 	for _, h := range hl.Hosts {
-		output += fmt.Sprintln(h)
+		f.Hosts = append(f.Hosts, hostEntry{Host: h, HostInfo: hl.Info[h]})
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
 	}
 
-	return os.WriteFile(hostsFile, []byte(output), 0644)
-}
\ No newline at end of file
+	return os.WriteFile(hostsFile, append(data, '\n'), 0644)
+}
+
+// Each calls fn with every host in the list, expanding CIDR blocks,
+// ranges and name patterns and skipping duplicates, until fn returns
+// false. Entries that don't parse are passed on as they are
+func (hl *HostsList) Each(fn func(host string) bool) {
+	hl.each(func(_, host string) bool {
+		return fn(host)
+	})
+}
+
+// each is like Each, but also passes the entry host comes from
+func (hl *HostsList) each(fn func(entry, host string) bool) {
+	seen := map[string]bool{}
+	for _, entry := range hl.Hosts {
+		t, err := parseTarget(entry)
+		if err != nil {
+			t = single(entry)
+		}
+
+		done := t.each(func(host string) bool {
+			key := strings.ToLower(host)
+			if seen[key] {
+				return true
+			}
+			seen[key] = true
+			return fn(entry, host)
+		})
+		if !done {
+			return
+		}
+	}
+}
+
+// Expand returns the hosts in the list after expansion
+func (hl *HostsList) Expand() []string {
+	hosts := []string{}
+	hl.Each(func(host string) bool {
+		hosts = append(hosts, host)
+		return true
+	})
+
+	return hosts
+}
+
+// Target is a host and the ports to scan on it
+type Target struct {
+	Host  string
+	Ports []int
+}
+
+// Targets returns the hosts in the list, each with ports
+func (hl *HostsList) Targets(ports []int) []Target {
+	targets := []Target{}
+	hl.Each(func(host string) bool {
+		targets = append(targets, Target{Host: host, Ports: ports})
+		return true
+	})
+
+	return targets
+}
+
+// HostTargets returns the hosts in the list with the ports set for
+// their entry, or ports for entries without. Ports in exclude are left
+// out. A host in several entries gets the ports of the first one
+func (hl *HostsList) HostTargets(ports, exclude []int) ([]Target, error) {
+	specs := map[string][]int{}
+	var err error
+	targets := []Target{}
+	hl.each(func(entry, host string) bool {
+		spec := hl.PortSpec(entry)
+		if spec == "" {
+			targets = append(targets, Target{Host: host, Ports: ports})
+			return true
+		}
+
+		p, ok := specs[spec]
+		if !ok {
+			if p, err = ParsePorts(spec); err != nil {
+				err = fmt.Errorf("%s: %w", entry, err)
+				return false
+			}
+			p = ExcludePorts(p, exclude)
+			specs[spec] = p
+		}
+
+		targets = append(targets, Target{Host: host, Ports: p})
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
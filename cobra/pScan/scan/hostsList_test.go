@@ -1,8 +1,10 @@
 package scan_test
 
 import (
+	"bytes"
 	"errors"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/bedminer1/cobra/pScan/scan"
@@ -155,4 +157,234 @@ func TestLoadNoFile(t *testing.T) {
 	if err := hl.Load(tf.Name()); err != nil {
 		t.Errorf("expected no error, got %q instead\n", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestLoadLegacy(t *testing.T) {
+	tf, err := os.CreateTemp("", "pScan")
+	if err != nil {
+		t.Fatalf("error creating temp file: %q", err)
+	}
+	defer os.Remove(tf.Name())
+
+	if _, err := tf.WriteString("host1\n\nhost2\n"); err != nil {
+		t.Fatal(err)
+	}
+	tf.Close()
+
+	hl := &scan.HostsList{}
+	if err := hl.Load(tf.Name()); err != nil {
+		t.Fatalf("Error loading list from file: %q", err)
+	}
+
+	expect := []string{"host1", "host2"}
+	if !reflect.DeepEqual(hl.Hosts, expect) {
+		t.Fatalf("Expected hosts %v, got %v instead\n", expect, hl.Hosts)
+	}
+
+	// saving migrates the file to the new format
+	if err := hl.Save(tf.Name()); err != nil {
+		t.Fatalf("Error saving list to file: %q", err)
+	}
+
+	data, err := os.ReadFile(tf.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("{")) {
+		t.Errorf("Expected the file to be migrated to JSON, got %q\n", data)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tf, err := os.CreateTemp("", "pScan")
+	if err != nil {
+		t.Fatalf("error creating temp file: %q", err)
+	}
+	defer os.Remove(tf.Name())
+
+	if _, err := tf.WriteString(`{"hosts": [`); err != nil {
+		t.Fatal(err)
+	}
+	tf.Close()
+
+	hl := &scan.HostsList{}
+	if err := hl.Load(tf.Name()); !errors.Is(err, scan.ErrInvalidHostsFile) {
+		t.Errorf("Expected error %q, got %q instead\n", scan.ErrInvalidHostsFile, err)
+	}
+}
+
+func TestSaveLoadInfo(t *testing.T) {
+	hl1 := &scan.HostsList{}
+	for _, h := range []string{"db1", "web[01-02]"} {
+		if err := hl1.Add(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info := scan.HostInfo{
+		Groups:      []string{"web"},
+		Labels:      map[string]string{"env": "prod"},
+		Description: "front end",
+		Ports:       "80,443",
+	}
+	if err := hl1.Update("web[01-02]", func(i *scan.HostInfo) { *i = info }); err != nil {
+		t.Fatal(err)
+	}
+	hl1.SetGroup("web", scan.Group{Description: "web servers", Ports: "web"})
+
+	tf, err := os.CreateTemp("", "pScan")
+	if err != nil {
+		t.Fatalf("error creating temp file: %q", err)
+	}
+	defer os.Remove(tf.Name())
+
+	if err := hl1.Save(tf.Name()); err != nil {
+		t.Fatalf("Error saving list to file: %q", err)
+	}
+
+	hl2 := &scan.HostsList{}
+	if err := hl2.Load(tf.Name()); err != nil {
+		t.Fatalf("Error loading list from file: %q", err)
+	}
+
+	if !reflect.DeepEqual(hl1, hl2) {
+		t.Errorf("Expected list %+v, got %+v instead\n", hl1, hl2)
+	}
+}
+
+func TestUpdateNotFound(t *testing.T) {
+	hl := &scan.HostsList{}
+	err := hl.Update("host1", func(*scan.HostInfo) {})
+	if !errors.Is(err, scan.ErrNotExists) {
+		t.Errorf("Expected error %q, got %q instead\n", scan.ErrNotExists, err)
+	}
+}
+
+// infoList returns a list with web hosts in prod and staging and an
+// untagged db host
+func infoList(t *testing.T) *scan.HostsList {
+	t.Helper()
+
+	hl := &scan.HostsList{}
+	hosts := map[string]scan.HostInfo{
+		"web1":   {Groups: []string{"web"}, Labels: map[string]string{"env": "prod"}},
+		"web2":   {Groups: []string{"web"}, Labels: map[string]string{"env": "staging"}, Ports: "8080"},
+		"cache1": {Groups: []string{"cache", "web"}, Labels: map[string]string{"env": "prod"}},
+		"db1":    {},
+	}
+
+	for h, info := range hosts {
+		if err := hl.Add(h); err != nil {
+			t.Fatal(err)
+		}
+		if err := hl.Update(h, func(i *scan.HostInfo) { *i = info }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hl.SetGroup("web", scan.Group{Ports: "80,443"})
+	hl.SetGroup("cache", scan.Group{Ports: "6379"})
+	return hl
+}
+
+func TestFilter(t *testing.T) {
+	testCases := []struct {
+		name   string
+		groups []string
+		labels map[string]string
+		expect []string
+	}{
+		{name: "All", expect: []string{"cache1", "db1", "web1", "web2"}},
+		{name: "Group", groups: []string{"web"}, expect: []string{"cache1", "web1", "web2"}},
+		{name: "Groups", groups: []string{"cache", "nothing"}, expect: []string{"cache1"}},
+		{name: "Label", labels: map[string]string{"env": "prod"}, expect: []string{"cache1", "web1"}},
+		{
+			name:   "GroupAndLabel",
+			groups: []string{"web"},
+			labels: map[string]string{"env": "staging"},
+			expect: []string{"web2"},
+		},
+		{name: "NoMatch", labels: map[string]string{"env": "dev"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := infoList(t).Filter(tc.groups, tc.labels)
+			if !reflect.DeepEqual(hl.Hosts, tc.expect) {
+				t.Errorf("Expected hosts %v, got %v instead\n", tc.expect, hl.Hosts)
+			}
+		})
+	}
+}
+
+func TestHostTargets(t *testing.T) {
+	hl := infoList(t)
+
+	targets, err := hl.HostTargets([]int{22, 80}, []int{443})
+	if err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	// own ports first, then the first group with ports, then the default
+	expect := []scan.Target{
+		{Host: "cache1", Ports: []int{6379}},
+		{Host: "db1", Ports: []int{22, 80}},
+		{Host: "web1", Ports: []int{80}},
+		{Host: "web2", Ports: []int{8080}},
+	}
+
+	if !reflect.DeepEqual(targets, expect) {
+		t.Errorf("Expected targets %v, got %v instead\n", expect, targets)
+	}
+}
+
+func TestHostTargetsInvalidPorts(t *testing.T) {
+	hl := &scan.HostsList{}
+	if err := hl.Add("host1"); err != nil {
+		t.Fatal(err)
+	}
+	hl.Update("host1", func(i *scan.HostInfo) { i.Ports = "x" })
+
+	if _, err := hl.HostTargets([]int{22}, nil); !errors.Is(err, scan.ErrInvalidPort) {
+		t.Errorf("Expected error %q, got %q instead\n", scan.ErrInvalidPort, err)
+	}
+}
+
+func TestRemoveGroup(t *testing.T) {
+	hl := infoList(t)
+
+	if err := hl.RemoveGroup("web"); err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if _, ok := hl.Groups["web"]; ok {
+		t.Errorf("Expected group web to be deleted\n")
+	}
+
+	if groups := hl.Info["cache1"].Groups; !reflect.DeepEqual(groups, []string{"cache"}) {
+		t.Errorf("Expected groups [cache], got %v instead\n", groups)
+	}
+
+	if err := hl.RemoveGroup("web"); !errors.Is(err, scan.ErrNotExists) {
+		t.Errorf("Expected error %q, got %q instead\n", scan.ErrNotExists, err)
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := scan.ParseLabels([]string{"env=prod", "tier = 1", "owner="})
+	if err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	expect := map[string]string{"env": "prod", "tier": "1", "owner": ""}
+	if !reflect.DeepEqual(labels, expect) {
+		t.Errorf("Expected labels %v, got %v instead\n", expect, labels)
+	}
+
+	for _, l := range []string{"env", "=prod"} {
+		if _, err := scan.ParseLabels([]string{l}); !errors.Is(err, scan.ErrInvalidLabel) {
+			t.Errorf("Expected error %q for %q, got %q instead\n", scan.ErrInvalidLabel, l, err)
+		}
+	}
+}
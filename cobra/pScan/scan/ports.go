@@ -0,0 +1,131 @@
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPort = errors.New("invalid port")
+
+const (
+	minPort = 1
+	maxPort = 65535
+)
+
+// topPorts are the 100 most common open TCP ports, most common first,
+// as ranked by nmap
+var topPorts = []int{
+	80, 23, 443, 21, 22, 25, 3389, 110, 445, 139,
+	143, 53, 135, 3306, 8080, 1723, 111, 995, 993, 5900,
+	1025, 587, 8888, 199, 1720, 465, 548, 113, 81, 6001,
+	10000, 514, 5060, 179, 1026, 2000, 8443, 8000, 32768, 554,
+	26, 1433, 49152, 2001, 515, 8008, 49154, 1027, 5666, 646,
+	5000, 5631, 631, 49153, 8081, 2049, 88, 79, 5800, 106,
+	2121, 1110, 49155, 6000, 513, 990, 5357, 427, 49156, 543,
+	544, 5101, 144, 7, 389, 8009, 3128, 444, 9999, 5009,
+	7070, 5190, 3000, 5432, 1900, 3986, 13, 1029, 9, 5051,
+	6646, 49157, 1028, 873, 1755, 2717, 4899, 9100, 119, 37,
+}
+
+// portSets are the named sets accepted in port specs
+var portSets = map[string][]int{
+	"top100": topPorts,
+	"web":    {80, 443, 8000, 8008, 8080, 8081, 8443, 8888},
+	"db":     {1433, 1521, 3306, 5432, 6379, 9042, 11211, 27017},
+}
+
+// ParsePorts parses a port spec, a comma separated list of ports,
+// ranges like 1-1024 and the named sets top100, web and db. Ports are
+// returned in the order given, without duplicates
+func ParsePorts(spec string) ([]int, error) {
+	ports := []int{}
+	seen := map[int]bool{}
+	add := func(p int) {
+		if !seen[p] {
+			seen[p] = true
+			ports = append(ports, p)
+		}
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if set, ok := portSets[strings.ToLower(part)]; ok {
+			for _, p := range set {
+				add(p)
+			}
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := parsePort(first)
+		if err != nil {
+			return nil, err
+		}
+
+		to := from
+		if isRange {
+			if to, err = parsePort(last); err != nil {
+				return nil, err
+			}
+		}
+
+		if to < from {
+			return nil, fmt.Errorf("%w: range %q ends before it starts", ErrInvalidPort, part)
+		}
+
+		for p := from; p <= to; p++ {
+			add(p)
+		}
+	}
+
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("%w: no ports in %q", ErrInvalidPort, spec)
+	}
+
+	return ports, nil
+}
+
+func parsePort(s string) (int, error) {
+	p, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a number or a port set", ErrInvalidPort, s)
+	}
+
+	if p < minPort || p > maxPort {
+		return 0, fmt.Errorf("%w: %d is not between %d and %d", ErrInvalidPort, p, minPort, maxPort)
+	}
+
+	return p, nil
+}
+
+// TopPorts returns the n most common TCP ports
+func TopPorts(n int) ([]int, error) {
+	if n < 1 || n > len(topPorts) {
+		return nil, fmt.Errorf("%w: top ports must be between 1 and %d", ErrInvalidPort, len(topPorts))
+	}
+
+	return append([]int{}, topPorts[:n]...), nil
+}
+
+// ExcludePorts returns ports without the ones in exclude
+func ExcludePorts(ports, exclude []int) []int {
+	skip := make(map[int]bool, len(exclude))
+	for _, p := range exclude {
+		skip[p] = true
+	}
+
+	res := make([]int, 0, len(ports))
+	for _, p := range ports {
+		if !skip[p] {
+			res = append(res, p)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,81 @@
+package scan_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+)
+
+func TestParsePorts(t *testing.T) {
+	testCases := []struct {
+		name      string
+		spec      string
+		expectLen int
+		expect    []int
+		expectErr error
+	}{
+		{name: "Single", spec: "22", expect: []int{22}},
+		{name: "List", spec: "22,80, 443", expect: []int{22, 80, 443}},
+		{name: "Range", spec: "20-23", expect: []int{20, 21, 22, 23}},
+		{name: "Mixed", spec: "443,20-22,22", expect: []int{443, 20, 21, 22}},
+		{name: "Web", spec: "web", expect: []int{80, 443, 8000, 8008, 8080, 8081, 8443, 8888}},
+		{name: "DBUpper", spec: "DB,22", expect: []int{1433, 1521, 3306, 5432, 6379, 9042, 11211, 27017, 22}},
+		{name: "Top100", spec: "top100", expectLen: 100},
+		{name: "FullRange", spec: "1-65535", expectLen: 65535},
+		{name: "Zero", spec: "0", expectErr: scan.ErrInvalidPort},
+		{name: "TooHigh", spec: "1-65536", expectErr: scan.ErrInvalidPort},
+		{name: "Reversed", spec: "90-80", expectErr: scan.ErrInvalidPort},
+		{name: "UnknownSet", spec: "mail", expectErr: scan.ErrInvalidPort},
+		{name: "Empty", spec: " , ", expectErr: scan.ErrInvalidPort},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ports, err := scan.ParsePorts(tc.spec)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Fatalf("Expected error %q, got %q instead\n", tc.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %q\n", err)
+			}
+
+			if tc.expect != nil && !reflect.DeepEqual(ports, tc.expect) {
+				t.Errorf("Expected ports %v, got %v instead\n", tc.expect, ports)
+			}
+
+			if tc.expectLen != 0 && len(ports) != tc.expectLen {
+				t.Errorf("Expected %d ports, got %d instead\n", tc.expectLen, len(ports))
+			}
+		})
+	}
+}
+
+func TestTopPorts(t *testing.T) {
+	ports, err := scan.TopPorts(3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %q\n", err)
+	}
+
+	if !reflect.DeepEqual(ports, []int{80, 23, 443}) {
+		t.Errorf("Expected top 3 ports [80 23 443], got %v instead\n", ports)
+	}
+
+	for _, n := range []int{0, 101} {
+		if _, err := scan.TopPorts(n); !errors.Is(err, scan.ErrInvalidPort) {
+			t.Errorf("Expected error %q for %d ports, got %q instead\n", scan.ErrInvalidPort, n, err)
+		}
+	}
+}
+
+func TestExcludePorts(t *testing.T) {
+	ports := scan.ExcludePorts([]int{22, 80, 443, 8080}, []int{80, 8080, 9000})
+	if !reflect.DeepEqual(ports, []int{22, 443}) {
+		t.Errorf("Expected ports [22 443], got %v instead\n", ports)
+	}
+}
@@ -0,0 +1,136 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidResolver = errors.New("invalid resolver")
+
+// address families accepted by Options.Prefer
+const (
+	PreferIPv4 = "ipv4"
+	PreferIPv6 = "ipv6"
+)
+
+// ResolverAddress returns the address of the DNS server s, an IP
+// address with an optional port, 53 by default
+func ResolverAddress(s string) (string, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return net.JoinHostPort(addr.String(), "53"), nil
+	}
+
+	addrPort, err := netip.ParseAddrPort(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q is not an IP address with an optional port", ErrInvalidResolver, s)
+	}
+
+	return addrPort.String(), nil
+}
+
+// ValidatePrefer checks prefer is empty, PreferIPv4 or PreferIPv6
+func ValidatePrefer(prefer string) error {
+	switch prefer {
+	case "", PreferIPv4, PreferIPv6:
+		return nil
+	}
+
+	return fmt.Errorf("%w: unknown address family %q, use %s or %s", ErrInvalidResolver, prefer, PreferIPv4, PreferIPv6)
+}
+
+// newResolver returns a resolver asking the DNS server at server, or
+// the system's resolver if server is empty. Names in the hosts file of
+// the system are still resolved from it
+func newResolver(server string) (*net.Resolver, error) {
+	if server == "" {
+		return net.DefaultResolver, nil
+	}
+
+	addr, err := ResolverAddress(server)
+	if err != nil {
+		return nil, err
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}, nil
+}
+
+// lookup is what resolving a host found: its addresses, preferred
+// family first, and with reverse lookups the names of each address
+type lookup struct {
+	addrs []string
+	names map[string][]string
+	err   error
+}
+
+func resolve(ctx context.Context, r *net.Resolver, host string, opts Options) lookup {
+	addrs, err := r.LookupHost(ctx, host)
+	if err != nil {
+		return lookup{err: err}
+	}
+
+	l := lookup{addrs: preferred(addrs, opts.Prefer)}
+	if !opts.ReverseLookup {
+		return l
+	}
+
+	l.names = map[string][]string{}
+	for _, a := range l.addrs {
+		// addresses without names are fine
+		names, _ := r.LookupAddr(ctx, a)
+		for _, n := range names {
+			l.names[a] = append(l.names[a], strings.TrimSuffix(n, "."))
+		}
+	}
+
+	return l
+}
+
+// preferred sorts addrs with the addresses of the family prefer first,
+// keeping the order of the resolver otherwise
+func preferred(addrs []string, prefer string) []string {
+	if prefer == "" {
+		return addrs
+	}
+
+	rank := func(a string) int {
+		ip, err := netip.ParseAddr(a)
+		if err != nil || ip.Unmap().Is4() == (prefer == PreferIPv4) {
+			return 0
+		}
+		return 1
+	}
+
+	sorted := append([]string{}, addrs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+
+	return sorted
+}
+
+// namesOf returns the reverse lookup names of addrs, without duplicates
+func (l lookup) namesOf(addrs []string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, a := range addrs {
+		for _, n := range l.names[a] {
+			if !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,278 @@
+package scan_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+)
+
+// DNS record types the stand-in server answers
+const (
+	typeA    = 1
+	typePTR  = 12
+	typeAAAA = 28
+)
+
+type dnsRecord struct {
+	qtype uint16
+	// data is the address of A and AAAA records, the name of PTR ones
+	data string
+}
+
+// dnsServer starts a stand-in DNS server answering from records, keyed
+// by names with their trailing dot, and returns its address
+func dnsServer(t *testing.T, records map[string][]dnsRecord) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			if reply := dnsReply(buf[:n], records); reply != nil {
+				conn.WriteTo(reply, addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// dnsReply answers the question in query, ignoring any other section
+func dnsReply(query []byte, records map[string][]dnsRecord) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+
+	labels := []string{}
+	end := 12
+	for end < len(query) && query[end] != 0 {
+		l := int(query[end])
+		if end+1+l > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[end+1:end+1+l]))
+		end += 1 + l
+	}
+	end += 5
+	if end > len(query) {
+		return nil
+	}
+
+	name := strings.ToLower(strings.Join(labels, ".")) + "."
+	qtype := binary.BigEndian.Uint16(query[end-4:])
+
+	answers := [][]byte{}
+	for _, r := range records[name] {
+		if r.qtype == qtype {
+			answers = append(answers, rdata(r))
+		}
+	}
+
+	// response, recursion desired and available, NXDOMAIN for unknown
+	// names
+	flags := uint16(0x8180)
+	if _, ok := records[name]; !ok {
+		flags |= 3
+	}
+
+	reply := binary.BigEndian.AppendUint16(nil, binary.BigEndian.Uint16(query))
+	reply = binary.BigEndian.AppendUint16(reply, flags)
+	reply = binary.BigEndian.AppendUint16(reply, 1)
+	reply = binary.BigEndian.AppendUint16(reply, uint16(len(answers)))
+	reply = append(reply, 0, 0, 0, 0)
+	reply = append(reply, query[12:end]...)
+
+	for _, a := range answers {
+		// the name points to the question
+		reply = append(reply, 0xc0, 12)
+		reply = binary.BigEndian.AppendUint16(reply, qtype)
+		reply = append(reply, 0, 1, 0, 0, 0, 60)
+		reply = binary.BigEndian.AppendUint16(reply, uint16(len(a)))
+		reply = append(reply, a...)
+	}
+
+	return reply
+}
+
+func rdata(r dnsRecord) []byte {
+	if r.qtype != typePTR {
+		return netip.MustParseAddr(r.data).AsSlice()
+	}
+
+	data := []byte{}
+	for _, l := range strings.Split(strings.TrimSuffix(r.data, "."), ".") {
+		data = append(data, byte(len(l)))
+		data = append(data, l...)
+	}
+
+	return append(data, 0)
+}
+
+func TestRunResolver(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.42:0")
+	if err != nil {
+		t.Skipf("Can't listen on 127.0.0.42: %s", err)
+	}
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	server := dnsServer(t, map[string][]dnsRecord{
+		"web.test.": {
+			{typeA, "127.0.0.42"},
+			{typeA, "127.0.0.43"},
+		},
+		"dual.test.": {
+			{typeA, "127.0.0.42"},
+			{typeAAAA, "2001:db8::1"},
+		},
+		"42.0.0.127.in-addr.arpa.": {
+			{typePTR, "web.test."},
+		},
+	})
+
+	// the state of port on each address
+	states := map[string]string{
+		"127.0.0.42": "open",
+		"127.0.0.43": "closed",
+	}
+
+	t.Run("OneAddr", func(t *testing.T) {
+		targets := []scan.Target{{Host: "web.test", Ports: []int{port}}}
+		res := scan.RunTargets(targets, scan.Options{Resolver: server})
+
+		if len(res) != 1 {
+			t.Fatalf("Expected 1 result, got %d instead\n", len(res))
+		}
+
+		r := res[0]
+		if len(r.Addrs) != 2 || r.Addr != r.Addrs[0] {
+			t.Fatalf("Expected 2 addresses and the first scanned, got %v and %q instead\n", r.Addrs, r.Addr)
+		}
+
+		if s := r.PortStates[0].State.String(); s != states[r.Addr] {
+			t.Errorf("Expected port %d on %s %s, got %s instead\n", port, r.Addr, states[r.Addr], s)
+		}
+	})
+
+	t.Run("AllAddrs", func(t *testing.T) {
+		targets := []scan.Target{{Host: "web.test", Ports: []int{port}}}
+		res := scan.RunTargets(targets, scan.Options{Resolver: server, AllAddrs: true})
+
+		if len(res) != 2 {
+			t.Fatalf("Expected 2 results, got %d instead\n", len(res))
+		}
+
+		for _, r := range res {
+			if r.Host != "web.test" {
+				t.Errorf("Expected host web.test, got %q instead\n", r.Host)
+			}
+
+			if s := r.PortStates[0].State.String(); s != states[r.Addr] {
+				t.Errorf("Expected port %d on %s %s, got %s instead\n", port, r.Addr, states[r.Addr], s)
+			}
+		}
+
+		if res[0].Addr == res[1].Addr {
+			t.Errorf("Expected each address scanned once, got %q twice\n", res[0].Addr)
+		}
+	})
+
+	t.Run("Prefer", func(t *testing.T) {
+		testCases := []struct {
+			prefer string
+			expect []string
+		}{
+			{scan.PreferIPv4, []string{"127.0.0.42", "2001:db8::1"}},
+			{scan.PreferIPv6, []string{"2001:db8::1", "127.0.0.42"}},
+		}
+
+		for _, tc := range testCases {
+			targets := []scan.Target{{Host: "dual.test"}}
+			res := scan.RunTargets(targets, scan.Options{Resolver: server, Prefer: tc.prefer})
+
+			if !reflect.DeepEqual(res[0].Addrs, tc.expect) || res[0].Addr != tc.expect[0] {
+				t.Errorf("%s: expected addresses %v, got %v scanning %q instead\n", tc.prefer, tc.expect, res[0].Addrs, res[0].Addr)
+			}
+		}
+	})
+
+	t.Run("Reverse", func(t *testing.T) {
+		targets := []scan.Target{{Host: "web.test"}}
+		res := scan.RunTargets(targets, scan.Options{Resolver: server, AllAddrs: true, ReverseLookup: true})
+
+		for _, r := range res {
+			var expect []string
+			if r.Addr == "127.0.0.42" {
+				expect = []string{"web.test"}
+			}
+
+			if !reflect.DeepEqual(r.Names, expect) {
+				t.Errorf("Expected names %v for %s, got %v instead\n", expect, r.Addr, r.Names)
+			}
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		targets := []scan.Target{{Host: "missing.test", Ports: []int{port}}}
+		res := scan.RunTargets(targets, scan.Options{Resolver: server})
+
+		if len(res) != 1 || !res[0].NotFound {
+			t.Errorf("Expected missing.test not found, got %+v instead\n", res)
+		}
+	})
+}
+
+func TestResolverAddress(t *testing.T) {
+	testCases := []struct {
+		addr      string
+		expect    string
+		expectErr error
+	}{
+		{"10.0.0.53", "10.0.0.53:53", nil},
+		{"10.0.0.53:5353", "10.0.0.53:5353", nil},
+		{"::1", "[::1]:53", nil},
+		{"[::1]:5353", "[::1]:5353", nil},
+		{"dns.example.com", "", scan.ErrInvalidResolver},
+		{"10.0.0.53:dns", "", scan.ErrInvalidResolver},
+	}
+
+	for _, tc := range testCases {
+		addr, err := scan.ResolverAddress(tc.addr)
+		if !errors.Is(err, tc.expectErr) {
+			t.Errorf("%s: expected error %v, got %v instead\n", tc.addr, tc.expectErr, err)
+		}
+
+		if addr != tc.expect {
+			t.Errorf("%s: expected %q, got %q instead\n", tc.addr, tc.expect, addr)
+		}
+	}
+}
+
+func TestValidatePrefer(t *testing.T) {
+	for _, prefer := range []string{"", scan.PreferIPv4, scan.PreferIPv6} {
+		if err := scan.ValidatePrefer(prefer); err != nil {
+			t.Errorf("%q: unexpected error %q\n", prefer, err)
+		}
+	}
+
+	if err := scan.ValidatePrefer("ipv5"); !errors.Is(err, scan.ErrInvalidResolver) {
+		t.Errorf("Expected error %q, got %v instead\n", scan.ErrInvalidResolver, err)
+	}
+}
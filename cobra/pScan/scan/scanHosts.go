@@ -1,69 +1,492 @@
 package scan
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"sync"
+	"syscall"
 	"time"
 )
 
 type PortState struct {
-	Port int
-	Open state
+	Port  int
+	Proto string
+	State state
+	// Latency is the time the connection took, or for UDP the time
+	// until the reply
+	Latency time.Duration
+	// Err is the error behind StateError
+	Err error
+	// Service and Version are what runs on an open port, set with
+	// Options.ServiceDetect when recognised
+	Service string
+	Version string
+	// TLS is what a TLS handshake with an open port showed, set with
+	// Options.TLSInspect for ports that talk TLS
+	TLS *TLSInfo
 }
 
-// true -> open, false -> closed
-type state bool
+// protocols a port can be scanned with
+const (
+	ProtoTCP = "tcp"
+	ProtoUDP = "udp"
+)
+
+type state int
+
+// port states. A refused connection is closed, one that times out or
+// is rejected by a firewall is filtered. UDP ports that don't answer
+// are StateOpenFiltered as there is no way to tell an open port from a
+// dropped probe. StateError is for failures on our side, like running
+// out of file descriptors
+const (
+	StateClosed state = iota
+	StateOpen
+	StateOpenFiltered
+	StateFiltered
+	StateError
+)
 
 func (s state) String() string {
-	if s {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
 		return "open"
+	case StateOpenFiltered:
+		return "open|filtered"
+	case StateFiltered:
+		return "filtered"
+	}
+
+	return "error"
+}
+
+// ParseState returns the state named s, as returned by String
+func ParseState(s string) (state, error) {
+	for st := StateClosed; st <= StateError; st++ {
+		if st.String() == s {
+			return st, nil
+		}
+	}
+
+	return StateError, fmt.Errorf("unknown port state %q", s)
+}
+
+// classify returns the state of a port the connection to which failed
+// with err
+func classify(err error) state {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return StateClosed
+	case errors.As(err, &netErr) && netErr.Timeout(),
+		errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH),
+		errors.Is(err, syscall.EACCES):
+		return StateFiltered
+	}
+
+	return StateError
+}
+
+// Default scan options, used when the Options fields are not set
+const (
+	DefaultWorkers = 50
+	DefaultTimeout = 1 * time.Second
+)
+
+// Options configures a scan
+type Options struct {
+	// Workers is the number of connections attempted at the same time
+	Workers int
+	// Rate limits the connections started per second across all
+	// workers, 0 means no limit
+	Rate int
+	// Timeout is the time to wait for each connection, or for a reply
+	// to each UDP probe
+	Timeout time.Duration
+	// UDP scans UDP ports instead of TCP ones
+	UDP bool
+	// ServiceDetect probes open ports to find out which service and
+	// version run on them
+	ServiceDetect bool
+	// TLSInspect completes a TLS handshake with open ports to report
+	// their certificate
+	TLSInspect bool
+	// ExpiryDays is how close to their expiry date certificates are
+	// flagged as expiring
+	ExpiryDays int
+	// Resolver is the address of the DNS server looking up the hosts,
+	// the system's resolver if empty
+	Resolver string
+	// Prefer, PreferIPv4 or PreferIPv6, puts the addresses of that
+	// family first
+	Prefer string
+	// AllAddrs scans every address of each host instead of only one
+	AllAddrs bool
+	// ReverseLookup looks up the names of the addresses of each host
+	ReverseLookup bool
+	// SkipDown pings the hosts before the scan, see Discover, and skips
+	// the ports of those that don't answer
+	SkipDown bool
+	// PingPorts are the ports of the TCP ping, DefaultPingPorts if
+	// empty
+	PingPorts []int
+	// ICMP sends an ICMP echo with the TCP ping, it needs root
+	// privileges
+	ICMP bool
+	// OnProgress, when set, is called after each port is scanned with
+	// the number of ports scanned so far and the total
+	OnProgress func(done, total int)
+	// OnResult, when set, is called with the index and results of each
+	// host as soon as all its ports are scanned. The results must not
+	// be changed. Neither callback is called concurrently
+	OnResult func(i int, r Results)
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = DefaultWorkers
+	}
+
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
 	}
 
-	return "closed"
+	return o
 }
 
-// scanPort performs port scan on single TCP port
-func scanPort(host string, port int) PortState {
+// dialContext opens the connections, tests replace it to simulate
+// network latency
+var dialContext = func(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	d := net.Dialer{Timeout: timeout}
+	return d.DialContext(ctx, network, address)
+}
+
+// scanPort performs port scan on single TCP port of host, connecting
+// to addr
+func scanPort(ctx context.Context, host, addr string, port int, opts Options) PortState {
 	p := PortState{
-		Port: port,
-		Open: false,
+		Port:  port,
+		Proto: ProtoTCP,
 	}
 
-	address := net.JoinHostPort(host, fmt.Sprintf("%d", port))
-	scanConn, err := net.DialTimeout("tcp", address, 1*time.Second)
+	address := net.JoinHostPort(addr, fmt.Sprintf("%d", port))
+	start := time.Now()
+	scanConn, err := dialContext(ctx, "tcp", address, opts.Timeout)
+	p.Latency = time.Since(start)
 	if err != nil {
-		return p // returns with port being closed
+		p.State = classify(err)
+		if p.State == StateError {
+			p.Err = err
+		}
+		return p
+	}
+
+	defer scanConn.Close()
+	p.State = StateOpen
+
+	if opts.ServiceDetect {
+		p.Service, p.Version = detectService(ctx, scanConn, host, addr, port, opts.Timeout)
+	}
+
+	if opts.TLSInspect {
+		p.TLS = inspectTLS(ctx, host, addr, port, opts)
 	}
 
-	scanConn.Close()
-	p.Open = true
 	return p
 }
 
 // Results represents scan results for a single host
 type Results struct {
 	Host string
+	// Addrs are the addresses Host resolved to
+	Addrs []string
+	// Addr is the address scanned, empty when the connections were
+	// made to Host
+	Addr string
+	// Names are the reverse lookup names of Addr, or of Addrs
+	Names []string
 	NotFound bool
+	// Down is set for hosts that didn't answer the ping of
+	// Options.SkipDown, their ports weren't scanned
+	Down bool
 	PortStates []PortState
 }
 
-// Run performs port scan on hosts list
-func Run(hl *HostsList, ports []int) []Results {
-	res := make([]Results, 0, len(hl.Hosts))
-	for _, h := range hl.Hosts {
-		r := Results{
-			Host: h,
+var ErrInvalidRate = errors.New("invalid rate")
+
+// MaxRate is the highest rate a limiter can keep, one event per
+// nanosecond
+const MaxRate = int(time.Second)
+
+// ValidateRate checks rate is between 0, no limit, and MaxRate
+func ValidateRate(rate int) error {
+	if rate < 0 || rate > MaxRate {
+		return fmt.Errorf("%w: %d is not between 0 and %d", ErrInvalidRate, rate, MaxRate)
+	}
+
+	return nil
+}
+
+// limiter spaces out events to at most rate per second, a nil limiter
+// doesn't wait
+type limiter struct {
+	ticker *time.Ticker
+}
+
+func newLimiter(rate int) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	// higher rates than MaxRate get no closer than it
+	interval := max(time.Second/time.Duration(rate), time.Nanosecond)
+	return &limiter{ticker: time.NewTicker(interval)}
+}
+
+// wait waits for the next event, and reports false if ctx is done first
+func (l *limiter) wait(ctx context.Context) bool {
+	if l == nil {
+		return ctx.Err() == nil
+	}
+
+	select {
+	case <-l.ticker.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l *limiter) stop() {
+	if l != nil {
+		l.ticker.Stop()
+	}
+}
+
+// pool runs job for every index in [0, n) using workers goroutines,
+// until ctx is done
+func pool(ctx context.Context, n, workers int, job func(i int)) {
+	if workers > n {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				job(i)
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
 		}
-		if _, err := net.LookupHost(h); err != nil {
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// Run performs port scan on hosts list, scanning ports on every host
+func Run(hl *HostsList, ports []int, opts Options) []Results {
+	return RunTargets(hl.Targets(ports), opts)
+}
+
+// RunTargets scans the ports of every target. Hosts and ports are
+// scanned concurrently, but the results are in the same order as the
+// targets and their ports
+func RunTargets(targets []Target, opts Options) []Results {
+	res, _ := RunContext(context.Background(), targets, opts)
+	return res
+}
+
+// RunContext is like RunTargets, but stops when ctx is done. It then
+// returns the hosts looked up so far, with the ports scanned on them,
+// and the error of ctx. With opts.AllAddrs, there are results for each
+// address of a host. With opts.SkipDown, hosts that don't answer the
+// ping are marked down and not scanned
+func RunContext(ctx context.Context, targets []Target, opts Options) ([]Results, error) {
+	opts = opts.withDefaults()
+
+	resolver, err := newResolver(opts.Resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	lookups := make([]*lookup, len(targets))
+	pool(ctx, len(targets), opts.Workers, func(i int) {
+		l := resolve(ctx, resolver, targets[i].Host, opts)
+		if !stopped(ctx) {
+			lookups[i] = &l
+		}
+	})
+
+	// one entry per host, or per address, of the targets looked up.
+	// dial is where the connections go and scanned tracks the ports
+	// scanned
+	res := []Results{}
+	dial := []string{}
+	ports := [][]int{}
+	for i, l := range lookups {
+		if l == nil {
+			continue
+		}
+
+		host := targets[i].Host
+		if l.err != nil {
 			// hosts not found
-			r.NotFound = true
-			res = append(res, r)
+			res = append(res, Results{Host: host, NotFound: true})
+			dial = append(dial, host)
+			ports = append(ports, nil)
 			continue
 		}
-		for _, p := range ports {
-			r.PortStates = append(r.PortStates, scanPort(h, p))
+
+		addrs := []string{""}
+		switch {
+		case opts.AllAddrs:
+			addrs = l.addrs
+		case opts.Resolver != "" || opts.Prefer != "":
+			// dialing host would resolve it again, with the system's
+			// resolver and order
+			addrs = l.addrs[:1]
+		}
+
+		for _, a := range addrs {
+			r := Results{Host: host, Addrs: l.addrs, Addr: a, Names: l.namesOf(l.addrs)}
+			d := host
+			if a != "" {
+				r.Names = l.namesOf([]string{a})
+				d = a
+			}
+			if len(targets[i].Ports) > 0 {
+				r.PortStates = make([]PortState, len(targets[i].Ports))
+			}
+
+			res = append(res, r)
+			dial = append(dial, d)
+			ports = append(ports, targets[i].Ports)
 		}
-		res = append(res, r)
 	}
-	return res
-}
\ No newline at end of file
+
+	if opts.SkipDown {
+		if err := skipDown(ctx, res, dial, ports, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// one job per host and port, each writes to its own slot in res
+	type job struct {
+		host, port int
+	}
+
+	jobs := []job{}
+	left := make([]int, len(res))
+	scanned := make([][]bool, len(res))
+	for h := range res {
+		scanned[h] = make([]bool, len(ports[h]))
+		for p := range ports[h] {
+			jobs = append(jobs, job{h, p})
+		}
+		left[h] = len(ports[h])
+	}
+
+	// hosts without ports to scan are done already
+	if opts.OnResult != nil {
+		for h := range res {
+			if left[h] == 0 {
+				opts.OnResult(h, res[h])
+			}
+		}
+	}
+
+	l := newLimiter(opts.Rate)
+	defer l.stop()
+
+	var mu sync.Mutex
+	done := 0
+	pool(ctx, len(jobs), opts.Workers, func(i int) {
+		j := jobs[i]
+		if !l.wait(ctx) {
+			return
+		}
+
+		var p PortState
+		if opts.UDP {
+			p = scanUDPPort(ctx, dial[j.host], ports[j.host][j.port], opts)
+		} else {
+			p = scanPort(ctx, res[j.host].Host, dial[j.host], ports[j.host][j.port], opts)
+		}
+		if stopped(ctx) {
+			// the scan may have been cut short
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		res[j.host].PortStates[j.port] = p
+		scanned[j.host][j.port] = true
+		done++
+		left[j.host]--
+
+		if opts.OnProgress != nil {
+			opts.OnProgress(done, len(jobs))
+		}
+		if opts.OnResult != nil && left[j.host] == 0 {
+			opts.OnResult(j.host, res[j.host])
+		}
+	})
+
+	if stopped(ctx) {
+		err := ctx.Err()
+		if err == nil {
+			err = context.DeadlineExceeded
+		}
+		return partial(res, scanned), err
+	}
+
+	return res, nil
+}
+
+// stopped reports whether ctx is done. Dials give up at the deadline of
+// ctx, which can be just before ctx reports it's done
+func stopped(ctx context.Context) bool {
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return true
+	}
+
+	return ctx.Err() != nil
+}
+
+// partial returns the hosts in res with only the ports scanned on
+// them, keeping their indexes
+func partial(res []Results, scanned [][]bool) []Results {
+	part := []Results{}
+	for h, r := range res {
+		ports := []PortState{}
+		for p, ok := range scanned[h] {
+			if ok {
+				ports = append(ports, r.PortStates[p])
+			}
+		}
+		if r.PortStates != nil {
+			r.PortStates = ports
+		}
+
+		part = append(part, r)
+	}
+
+	return part
+}
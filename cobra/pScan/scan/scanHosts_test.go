@@ -1,9 +1,16 @@
 package scan_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net"
+	"os"
+	"reflect"
 	"strconv"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/bedminer1/cobra/pScan/scan"
 )
@@ -12,13 +19,45 @@ import (
 func TestStateString(t *testing.T) {
 	ps := scan.PortState{}
 
-	if ps.Open.String() != "closed" {
-		t.Errorf("Expected port state to be closed, got %s\n", ps.Open.String())
+	if ps.State.String() != "closed" {
+		t.Errorf("Expected port state to be closed, got %s\n", ps.State.String())
 	}
 
-	ps.Open = true
-	if ps.Open.String() != "open" {
-		t.Errorf("Expected port state to be open, got %s\n", ps.Open.String())
+	testCases := []struct {
+		state  string
+		expect string
+	}{
+		{scan.StateOpen.String(), "open"},
+		{scan.StateOpenFiltered.String(), "open|filtered"},
+		{scan.StateFiltered.String(), "filtered"},
+		{scan.StateError.String(), "error"},
+	}
+
+	for _, tc := range testCases {
+		if tc.state != tc.expect {
+			t.Errorf("Expected port state to be %s, got %s\n", tc.expect, tc.state)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		expect string
+	}{
+		{"Refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, "closed"},
+		{"Timeout", &net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}, "filtered"},
+		{"Unreachable", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)}, "filtered"},
+		{"Other", &net.OpError{Op: "dial", Err: os.NewSyscallError("socket", syscall.EMFILE)}, "error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if s := scan.Classify(tc.err); s.String() != tc.expect {
+				t.Errorf("Expected %s, got %s\n", tc.expect, s)
+			}
+		})
 	}
 }
 
@@ -59,7 +98,7 @@ func TestRunHostFound(t *testing.T) {
 		}
 	}
 
-	res := scan.Run(hl, ports)
+	res := scan.Run(hl, ports, scan.Options{})
 	if len(res) != 1 {
 		t.Fatalf("expected 1 result, got %d instead\n", len(res))
 	}
@@ -81,7 +120,7 @@ func TestRunHostFound(t *testing.T) {
 			t.Errorf("expected port %d, got %d\n", ports[i], res[0].PortStates[i].Port)
 		}
 
-		if res[0].PortStates[i].Open.String() != tc.expectState {
+		if res[0].PortStates[i].State.String() != tc.expectState {
 			t.Errorf("Expected port %d to be %s\n", ports[i], tc.expectState)
 		}
 	}
@@ -92,7 +131,7 @@ func TestRunHostNotFound(t *testing.T) {
 	hl := &scan.HostsList{}
 	hl.Add(host)
 
-	res := scan.Run(hl, []int{})
+	res := scan.Run(hl, []int{}, scan.Options{})
 
 	if len(res) != 1 {
 		t.Fatalf("expected 1 result, got %d instead\n", len(res))
@@ -109,4 +148,226 @@ func TestRunHostNotFound(t *testing.T) {
 	if len(res[0].PortStates) != 0 {
 		t.Fatalf("Expected 0 port states, got %d\n", len(res[0].PortStates))
 	}
-}
\ No newline at end of file
+}
+func TestRunOrder(t *testing.T) {
+	hosts := []string{"localhost", "389.389.389.389", "127.0.0.1"}
+	hl := &scan.HostsList{}
+	for _, h := range hosts {
+		hl.Add(h)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	open := ln.Addr().(*net.TCPAddr).Port
+	ports := []int{}
+	for i := 0; i < 20; i++ {
+		ports = append(ports, open)
+	}
+
+	res := scan.Run(hl, ports, scan.Options{Workers: 8})
+	if len(res) != len(hosts) {
+		t.Fatalf("expected %d results, got %d instead\n", len(hosts), len(res))
+	}
+
+	for i, r := range res {
+		if r.Host != hl.Hosts[i] {
+			t.Errorf("expected host %q at %d, got %q\n", hl.Hosts[i], i, r.Host)
+		}
+
+		if r.NotFound {
+			if len(r.PortStates) != 0 {
+				t.Errorf("expected no port states for %q\n", r.Host)
+			}
+			continue
+		}
+
+		if len(r.PortStates) != len(ports) {
+			t.Fatalf("expected %d port states, got %d\n", len(ports), len(r.PortStates))
+		}
+
+		for j, ps := range r.PortStates {
+			if ps.Port != ports[j] || ps.State != scan.StateOpen {
+				t.Errorf("expected port %d open at %d, got %d %s\n", ports[j], j, ps.Port, ps.State)
+			}
+
+			if ps.Latency <= 0 {
+				t.Errorf("expected latency to be recorded for port %d\n", ps.Port)
+			}
+		}
+	}
+}
+
+func TestRunRate(t *testing.T) {
+	hl := &scan.HostsList{}
+	hl.Add("localhost")
+
+	ports := []int{}
+	for i := 1; i <= 10; i++ {
+		ports = append(ports, i)
+	}
+
+	start := time.Now()
+	scan.Run(hl, ports, scan.Options{Workers: 10, Rate: 50})
+
+	// 10 connections at 50 per second take at least 9 intervals of 20ms
+	if d := time.Since(start); d < 180*time.Millisecond {
+		t.Errorf("expected rate limit to slow the scan down, took %s\n", d)
+	}
+}
+
+func TestValidateRate(t *testing.T) {
+	testCases := []struct {
+		rate      int
+		expectErr error
+	}{
+		{0, nil},
+		{50, nil},
+		{scan.MaxRate, nil},
+		{-1, scan.ErrInvalidRate},
+		{scan.MaxRate + 1, scan.ErrInvalidRate},
+	}
+
+	for _, tc := range testCases {
+		if err := scan.ValidateRate(tc.rate); !errors.Is(err, tc.expectErr) {
+			t.Errorf("%d: expected error %v, got %v instead\n", tc.rate, tc.expectErr, err)
+		}
+	}
+
+	// rates too high to space out don't stop the scan
+	hl := &scan.HostsList{}
+	hl.Add("localhost")
+	if res := scan.Run(hl, []int{1}, scan.Options{Rate: 2 * scan.MaxRate}); len(res) != 1 {
+		t.Errorf("Expected 1 result, got %d instead\n", len(res))
+	}
+}
+
+func TestRunContextCallbacks(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	open := ln.Addr().(*net.TCPAddr).Port
+	targets := []scan.Target{
+		{Host: "127.0.0.1", Ports: []int{open, open, open}},
+		{Host: "389.389.389.389", Ports: []int{open}},
+		{Host: "localhost", Ports: []int{open, open}},
+	}
+
+	progress := []int{}
+	done := map[int]int{}
+	opts := scan.Options{
+		Workers: 4,
+		OnProgress: func(n, total int) {
+			if total != 5 {
+				t.Errorf("expected 5 ports in total, got %d\n", total)
+			}
+			progress = append(progress, n)
+		},
+		OnResult: func(i int, r scan.Results) {
+			done[i]++
+			if r.Host != targets[i].Host {
+				t.Errorf("expected host %q at %d, got %q\n", targets[i].Host, i, r.Host)
+			}
+			if !r.NotFound && len(r.PortStates) != len(targets[i].Ports) {
+				t.Errorf("expected %d ports for %q, got %d\n", len(targets[i].Ports), r.Host, len(r.PortStates))
+			}
+		},
+	}
+
+	res, err := scan.RunContext(context.Background(), targets, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %q\n", err)
+	}
+
+	if len(res) != len(targets) {
+		t.Fatalf("expected %d results, got %d instead\n", len(targets), len(res))
+	}
+
+	if !reflect.DeepEqual(progress, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("expected progress 1 to 5, got %v\n", progress)
+	}
+
+	if !reflect.DeepEqual(done, map[int]int{0: 1, 1: 1, 2: 1}) {
+		t.Errorf("expected one result per host, got %v\n", done)
+	}
+}
+
+func TestRunContextCancel(t *testing.T) {
+	defer scan.SetDialDelay(50 * time.Millisecond)()
+
+	ports := []int{}
+	for i := 1; i <= 20; i++ {
+		ports = append(ports, i)
+	}
+	targets := []scan.Target{{Host: "localhost", Ports: ports}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	res, err := scan.RunContext(ctx, targets, scan.Options{Workers: 2})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected error %q, got %q instead\n", context.DeadlineExceeded, err)
+	}
+
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expected the scan to stop at the deadline, took %s\n", d)
+	}
+
+	if len(res) != 1 {
+		t.Fatalf("expected 1 result, got %d instead\n", len(res))
+	}
+
+	// 2 workers get through 4 ports in 120ms
+	n := len(res[0].PortStates)
+	if n == 0 || n == len(ports) {
+		t.Fatalf("expected some ports to be scanned, got %d\n", n)
+	}
+
+	for i, p := range res[0].PortStates {
+		if p.Port != ports[i] || p.State != scan.StateClosed {
+			t.Errorf("expected port %d closed, got %d %s\n", ports[i], p.Port, p.State)
+		}
+	}
+}
+
+func TestRunContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	res, err := scan.RunContext(ctx, []scan.Target{{Host: "localhost", Ports: []int{22}}}, scan.Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected error %q, got %q instead\n", context.Canceled, err)
+	}
+
+	if len(res) != 0 {
+		t.Errorf("expected no results, got %v\n", res)
+	}
+}
+
+func BenchmarkRun(b *testing.B) {
+	// localhost answers at once, add some latency to see the gain
+	defer scan.SetDialDelay(2 * time.Millisecond)()
+
+	hl := &scan.HostsList{}
+	hl.Add("localhost")
+
+	ports := []int{}
+	for i := 1; i <= 100; i++ {
+		ports = append(ports, i)
+	}
+
+	for _, workers := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("Workers%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				scan.Run(hl, ports, scan.Options{Workers: workers, Timeout: 100 * time.Millisecond})
+			}
+		})
+	}
+}
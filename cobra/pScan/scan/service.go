@@ -0,0 +1,129 @@
+package scan
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxBannerWait limits how long to wait for a service to greet us
+// before probing it
+const maxBannerWait = 500 * time.Millisecond
+
+// signature identifies a service from its first reply, the first
+// capture group of pattern, if any, is the version
+type signature struct {
+	service string
+	pattern *regexp.Regexp
+}
+
+// signatures are tried in order, more specific ones first
+var signatures = []signature{
+	{"ssh", regexp.MustCompile(`^SSH-[\d.]+-(\S+)`)},
+	{"smtp", regexp.MustCompile(`^220[ -]\S+ E?SMTP ?([^\r\n]*)`)},
+	{"ftp", regexp.MustCompile(`(?i)^220[ -]([^\r\n]*ftp[^\r\n]*)`)},
+	{"pop3", regexp.MustCompile(`^\+OK ?([^\r\n]*)`)},
+	{"imap", regexp.MustCompile(`^\* OK ?([^\r\n]*)`)},
+	{"mysql", regexp.MustCompile(`(?s)^.\x00\x00\x00\x0a([\d.]+[^\x00]*)\x00`)},
+	{"http", regexp.MustCompile(`(?s)^HTTP/1\.[01] \d{3}(?:.*?\r\n[Ss]erver: ([^\r\n]+))?`)},
+	{"tls", regexp.MustCompile(`^\x15\x03[\x00-\x04]`)},
+}
+
+// udpServices names the services answering the UDP probes
+var udpServices = map[int]string{
+	53:  "dns",
+	123: "ntp",
+	161: "snmp",
+}
+
+// match returns the service and version in reply
+func match(reply []byte) (string, string, bool) {
+	for _, s := range signatures {
+		m := s.pattern.FindSubmatch(reply)
+		if m == nil {
+			continue
+		}
+
+		version := ""
+		if len(m) > 1 {
+			version = strings.TrimSpace(string(m[1]))
+		}
+		return s.service, version, true
+	}
+
+	return "", "", false
+}
+
+// readReply reads what the other side sends within wait
+func readReply(conn net.Conn, wait time.Duration) []byte {
+	if err := conn.SetReadDeadline(time.Now().Add(wait)); err != nil {
+		return nil
+	}
+
+	buf := make([]byte, 4096)
+	n, _ := conn.Read(buf)
+	return buf[:n]
+}
+
+func httpProbe(host string) []byte {
+	return []byte(fmt.Sprintf("HEAD / HTTP/1.0\r\nHost: %s\r\n\r\n", host))
+}
+
+// detectService finds out what runs on the port conn is connected to.
+// It reads the banner of services that speak first, then tries a TLS
+// handshake, then an HTTP HEAD request. host is the name the port is
+// scanned for, addr where connections go
+func detectService(ctx context.Context, conn net.Conn, host, addr string, port int, timeout time.Duration) (string, string) {
+	if banner := readReply(conn, min(timeout, maxBannerWait)); len(banner) > 0 {
+		service, version, _ := match(banner)
+		return service, version
+	}
+
+	if service, version, ok := tlsProbe(ctx, host, addr, port, timeout); ok {
+		return service, version
+	}
+
+	if _, err := conn.Write(httpProbe(host)); err != nil {
+		return "", ""
+	}
+
+	service, version, _ := match(readReply(conn, timeout))
+	return service, version
+}
+
+// tlsProbe tries a TLS handshake on a new connection. It reports https
+// if an HTTP server answers over it, or tls with the protocol version
+func tlsProbe(ctx context.Context, host, addr string, port int, timeout time.Duration) (string, string, bool) {
+	conn, err := dialContext(ctx, "tcp", net.JoinHostPort(addr, fmt.Sprint(port)), timeout)
+	if err != nil {
+		return "", "", false
+	}
+	defer conn.Close()
+
+	// we only want to know whether the port talks TLS, not whether we
+	// trust it
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+
+	if err := tlsConn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return "", "", false
+	}
+
+	if err := tlsConn.Handshake(); err != nil {
+		return "", "", false
+	}
+
+	if _, err := tlsConn.Write(httpProbe(host)); err == nil {
+		if service, version, ok := match(readReply(tlsConn, timeout)); ok && service == "http" {
+			return "https", version, true
+		}
+	}
+
+	return "tls", tls.VersionName(tlsConn.ConnectionState().Version), true
+}
@@ -0,0 +1,128 @@
+package scan_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+)
+
+// bannerServer accepts connections on localhost and greets them with
+// banner, or stays silent if banner is empty
+func bannerServer(t *testing.T, banner string) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				if banner != "" {
+					conn.Write([]byte(banner))
+				}
+				buf := make([]byte, 512)
+				conn.Read(buf)
+			}()
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func serverPort(t *testing.T, serverURL string) int {
+	t.Helper()
+
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return port
+}
+
+func TestServiceDetect(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "test-server/1.0")
+	})
+
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	testCases := []struct {
+		name          string
+		port          int
+		expectService string
+		expectVersion string
+	}{
+		{"SSH", bannerServer(t, "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3\r\n"), "ssh", "OpenSSH_9.6p1"},
+		{"SMTP", bannerServer(t, "220 mail.example.com ESMTP Postfix (Ubuntu)\r\n"), "smtp", "Postfix (Ubuntu)"},
+		{"FTP", bannerServer(t, "220 (vsFTPd 3.0.5)\r\n"), "ftp", "(vsFTPd 3.0.5)"},
+		{"MySQL", bannerServer(t, "\x4a\x00\x00\x00\x0a8.0.36\x00\x08\x00\x00\x00"), "mysql", "8.0.36"},
+		{"HTTP", serverPort(t, httpServer.URL), "http", "test-server/1.0"},
+		{"HTTPS", serverPort(t, tlsServer.URL), "https", "test-server/1.0"},
+		{"Unknown", bannerServer(t, "hello\r\n"), "", ""},
+		{"Silent", bannerServer(t, ""), "", ""},
+	}
+
+	hl := &scan.HostsList{}
+	hl.Add("127.0.0.1")
+
+	ports := []int{}
+	for _, tc := range testCases {
+		ports = append(ports, tc.port)
+	}
+
+	res := scan.Run(hl, ports, scan.Options{ServiceDetect: true, Timeout: 300 * time.Millisecond})
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := res[0].PortStates[i]
+			if ps.State != scan.StateOpen {
+				t.Fatalf("Expected port %d to be open, got %s\n", ps.Port, ps.State)
+			}
+
+			if ps.Service != tc.expectService {
+				t.Errorf("Expected service %q, got %q\n", tc.expectService, ps.Service)
+			}
+
+			if ps.Version != tc.expectVersion {
+				t.Errorf("Expected version %q, got %q\n", tc.expectVersion, ps.Version)
+			}
+		})
+	}
+}
+
+func TestServiceDetectOff(t *testing.T) {
+	hl := &scan.HostsList{}
+	hl.Add("127.0.0.1")
+
+	port := bannerServer(t, "SSH-2.0-OpenSSH_9.6\r\n")
+	res := scan.Run(hl, []int{port}, scan.Options{})
+
+	if res[0].PortStates[0].Service != "" {
+		t.Errorf("Expected no service without detection, got %q\n", res[0].PortStates[0].Service)
+	}
+}
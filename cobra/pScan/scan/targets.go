@@ -0,0 +1,268 @@
+package scan
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidHost = errors.New("invalid host")
+
+// maxExpand limits how many hosts a single entry can stand for
+const maxExpand = 1 << 16
+
+// target is a hosts list entry, either a single host or a pattern
+// standing for several hosts that is expanded when scanning
+type target interface {
+	// count returns the number of hosts in the target
+	count() int
+	// each calls fn with every host in the target until fn returns
+	// false, and reports whether it went through all of them
+	each(fn func(host string) bool) bool
+}
+
+// single is a host name or address
+type single string
+
+func (s single) count() int { return 1 }
+
+func (s single) each(fn func(string) bool) bool { return fn(string(s)) }
+
+// addrRange is n consecutive addresses starting at first, from a CIDR
+// block like 10.0.0.0/24 or a range like 192.168.1.10-50
+type addrRange struct {
+	first netip.Addr
+	n     int
+}
+
+func (r addrRange) count() int { return r.n }
+
+func (r addrRange) each(fn func(string) bool) bool {
+	a := r.first
+	for i := 0; i < r.n; i++ {
+		if !fn(a.String()) {
+			return false
+		}
+		a = a.Next()
+	}
+
+	return true
+}
+
+// namePattern is a host name with a numeric range like web[01-10].internal,
+// rest holds the part after the range, which can have ranges too
+type namePattern struct {
+	prefix   string
+	from, to int
+	width    int
+	rest     target
+}
+
+func (p namePattern) count() int { return (p.to - p.from + 1) * p.rest.count() }
+
+func (p namePattern) each(fn func(string) bool) bool {
+	for i := p.from; i <= p.to; i++ {
+		host := fmt.Sprintf("%s%0*d", p.prefix, p.width, i)
+		done := p.rest.each(func(rest string) bool {
+			return fn(host + rest)
+		})
+		if !done {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseTarget parses a hosts list entry. Entries that don't look like
+// a CIDR block, an address range or a name pattern are single hosts
+func parseTarget(entry string) (target, error) {
+	switch {
+	case strings.Contains(entry, "/"):
+		return parseCIDR(entry)
+	case strings.Contains(entry, "["):
+		t, err := parseName(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		// the hosts of a pattern only differ in their digits
+		first := ""
+		t.each(func(host string) bool {
+			first = host
+			return false
+		})
+		if !validHost(first) {
+			return nil, fmt.Errorf("%w: %s: not a valid host name pattern", ErrInvalidHost, entry)
+		}
+		return t, nil
+	}
+
+	if first, last, ok := strings.Cut(entry, "-"); ok {
+		if addr, err := netip.ParseAddr(first); err == nil {
+			return parseAddrRange(entry, addr, last)
+		}
+	}
+
+	if !validHost(entry) {
+		return nil, fmt.Errorf("%w: %q is not a host name or address", ErrInvalidHost, entry)
+	}
+
+	return single(entry), nil
+}
+
+// validHost reports whether host is an IP address or a host name made
+// of dot separated labels of letters, digits, hyphens and underscores
+func validHost(host string) bool {
+	if _, err := netip.ParseAddr(host); err == nil {
+		return true
+	}
+
+	name := strings.TrimSuffix(host, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func parseCIDR(entry string) (target, error) {
+	p, err := netip.ParsePrefix(entry)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidHost, err)
+	}
+
+	hostBits := p.Addr().BitLen() - p.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("%w: %s has more than %d hosts", ErrInvalidHost, entry, maxExpand)
+	}
+
+	return addrRange{first: p.Masked().Addr(), n: 1 << hostBits}, nil
+}
+
+// parseAddrRange parses the end of a range starting at first, either a
+// full address or, for IPv4, the last octet
+func parseAddrRange(entry string, first netip.Addr, end string) (target, error) {
+	last, err := netip.ParseAddr(end)
+	if err != nil {
+		octet, convErr := strconv.Atoi(end)
+		if convErr != nil || !first.Is4() || octet < 0 || octet > 255 {
+			return nil, fmt.Errorf("%w: %s: range must end with an address or, for IPv4, an octet", ErrInvalidHost, entry)
+		}
+
+		b := first.As4()
+		b[3] = byte(octet)
+		last = netip.AddrFrom4(b)
+	}
+
+	if first.BitLen() != last.BitLen() || last.Less(first) {
+		return nil, fmt.Errorf("%w: %s: range ends before it starts", ErrInvalidHost, entry)
+	}
+
+	n, ok := distance(first, last)
+	if !ok || n >= maxExpand {
+		return nil, fmt.Errorf("%w: %s has more than %d hosts", ErrInvalidHost, entry, maxExpand)
+	}
+
+	return addrRange{first: first, n: int(n) + 1}, nil
+}
+
+// distance returns last - first, if it fits in 64 bits
+func distance(first, last netip.Addr) (uint64, bool) {
+	a, b := first.As16(), last.As16()
+	if binary.BigEndian.Uint64(a[:8]) != binary.BigEndian.Uint64(b[:8]) {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint64(b[8:]) - binary.BigEndian.Uint64(a[8:]), true
+}
+
+func parseName(entry string) (target, error) {
+	open := strings.Index(entry, "[")
+	if open == -1 {
+		if strings.Contains(entry, "]") {
+			return nil, fmt.Errorf("%w: %s: unbalanced brackets", ErrInvalidHost, entry)
+		}
+		return single(entry), nil
+	}
+
+	closing := strings.Index(entry[open:], "]")
+	if closing == -1 {
+		return nil, fmt.Errorf("%w: %s: unbalanced brackets", ErrInvalidHost, entry)
+	}
+	closing += open
+
+	fromStr, toStr, ok := strings.Cut(entry[open+1:closing], "-")
+	from, fromErr := strconv.Atoi(fromStr)
+	to, toErr := strconv.Atoi(toStr)
+	if !ok || fromErr != nil || toErr != nil || from < 0 || to < from {
+		return nil, fmt.Errorf("%w: %s: brackets must hold a range like [01-10]", ErrInvalidHost, entry)
+	}
+
+	// [01-10] keeps the leading zeros
+	width := 0
+	if len(fromStr) == len(toStr) {
+		width = len(fromStr)
+	}
+
+	rest, err := parseName(entry[closing+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	// checked before counting, which could overflow
+	if to-from >= maxExpand || rest.count() > maxExpand/(to-from+1) {
+		return nil, fmt.Errorf("%w: %s has more than %d hosts", ErrInvalidHost, entry, maxExpand)
+	}
+
+	return namePattern{
+		prefix: entry[:open],
+		from:   from,
+		to:     to,
+		width:  width,
+		rest:   rest,
+	}, nil
+}
+
+// EntrySize returns the number of hosts a hosts list entry stands for,
+// or 0 if it's not valid
+func EntrySize(entry string) int {
+	t, err := parseTarget(entry)
+	if err != nil {
+		return 0
+	}
+
+	return t.count()
+}
+
+// EntryHosts returns the hosts a hosts list entry stands for, or nil if
+// it's not valid
+func EntryHosts(entry string) []string {
+	t, err := parseTarget(entry)
+	if err != nil {
+		return nil
+	}
+
+	hosts := make([]string, 0, t.count())
+	t.each(func(host string) bool {
+		hosts = append(hosts, host)
+		return true
+	})
+
+	return hosts
+}
@@ -0,0 +1,147 @@
+package scan_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+)
+
+func TestExpand(t *testing.T) {
+	testCases := []struct {
+		name      string
+		hosts     []string
+		expect    []string
+		expectLen int
+	}{
+		{
+			name:   "Single",
+			hosts:  []string{"localhost"},
+			expect: []string{"localhost"},
+		},
+		{
+			name:   "CIDR",
+			hosts:  []string{"10.0.0.5/30"},
+			expect: []string{"10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7"},
+		},
+		{
+			name:      "CIDR24",
+			hosts:     []string{"192.168.0.0/24"},
+			expectLen: 256,
+		},
+		{
+			name:   "OctetRange",
+			hosts:  []string{"192.168.1.10-12"},
+			expect: []string{"192.168.1.10", "192.168.1.11", "192.168.1.12"},
+		},
+		{
+			name:   "FullRange",
+			hosts:  []string{"10.0.0.254-10.0.1.1"},
+			expect: []string{"10.0.0.254", "10.0.0.255", "10.0.1.0", "10.0.1.1"},
+		},
+		{
+			name:   "IPv6CIDR",
+			hosts:  []string{"2001:db8::/126"},
+			expect: []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"},
+		},
+		{
+			name:   "IPv6Range",
+			hosts:  []string{"2001:db8::fe-2001:db8::101"},
+			expect: []string{"2001:db8::fe", "2001:db8::ff", "2001:db8::100", "2001:db8::101"},
+		},
+		{
+			name:   "NamePattern",
+			hosts:  []string{"web[08-10].internal"},
+			expect: []string{"web08.internal", "web09.internal", "web10.internal"},
+		},
+		{
+			name:   "NamePatternNoPadding",
+			hosts:  []string{"db[9-10]"},
+			expect: []string{"db9", "db10"},
+		},
+		{
+			name:   "NestedPattern",
+			hosts:  []string{"r[1-2]n[1-2]"},
+			expect: []string{"r1n1", "r1n2", "r2n1", "r2n2"},
+		},
+		{
+			name:   "HyphenatedName",
+			hosts:  []string{"my-host.example.com"},
+			expect: []string{"my-host.example.com"},
+		},
+		{
+			name:   "Dedup",
+			hosts:  []string{"10.0.0.0/31", "10.0.0.1", "10.0.0.1-2", "Host1", "host1"},
+			expect: []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "Host1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// bypass Add to keep the order and duplicates
+			hl := &scan.HostsList{Hosts: tc.hosts}
+
+			hosts := hl.Expand()
+			if tc.expect != nil && !reflect.DeepEqual(hosts, tc.expect) {
+				t.Errorf("Expected hosts %v, got %v instead\n", tc.expect, hosts)
+			}
+
+			if tc.expectLen != 0 && len(hosts) != tc.expectLen {
+				t.Errorf("Expected %d hosts, got %d instead\n", tc.expectLen, len(hosts))
+			}
+		})
+	}
+}
+
+func TestEachStops(t *testing.T) {
+	hl := &scan.HostsList{Hosts: []string{"10.0.0.0/24", "host1"}}
+
+	n := 0
+	hl.Each(func(host string) bool {
+		n++
+		return n < 3
+	})
+
+	if n != 3 {
+		t.Errorf("Expected Each to stop after 3 hosts, got %d\n", n)
+	}
+}
+
+func TestAddInvalidHost(t *testing.T) {
+	testCases := []struct {
+		name string
+		host string
+	}{
+		{"BadPrefix", "10.0.0.0/33"},
+		{"TooLarge", "10.0.0.0/8"},
+		{"IPv6TooLarge", "2001:db8::/64"},
+		{"BadOctet", "10.0.0.1-300"},
+		{"Reversed", "10.0.0.10-5"},
+		{"MixedFamilies", "10.0.0.1-2001:db8::1"},
+		{"Unbalanced", "web[01-10.internal"},
+		{"BadBrackets", "web[a-c]"},
+		{"ReversedPattern", "web[10-01]"},
+		{"Space", "web server"},
+		{"URL", "http://host1"},
+		{"LeadingHyphen", "-host1"},
+		{"EmptyLabel", "host1..example.com"},
+		{"BadPatternName", "web@[01-10]"},
+		{"PatternTooLarge", "web[0-9223372036854775807].x"},
+		{"NestedTooLarge", "web[0-999][0-999].x"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := &scan.HostsList{}
+			err := hl.Add(tc.host)
+			if !errors.Is(err, scan.ErrInvalidHost) {
+				t.Fatalf("Expected error %q, got %q instead\n", scan.ErrInvalidHost, err)
+			}
+
+			if scan.EntrySize(tc.host) != 0 {
+				t.Errorf("Expected size 0 for invalid entry %q\n", tc.host)
+			}
+		})
+	}
+}
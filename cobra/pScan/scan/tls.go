@@ -0,0 +1,83 @@
+package scan
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+)
+
+// TLSInfo is what a TLS handshake with a port showed about its
+// certificate and connection
+type TLSInfo struct {
+	Version string
+	Cipher  string
+	Subject string
+	Issuer  string
+	// SANs are the DNS names and addresses the certificate is for
+	SANs     []string
+	NotAfter time.Time
+	// Expired flags certificates past their expiry date, Expiring
+	// those within Options.ExpiryDays of it
+	Expired  bool
+	Expiring bool
+	// HostnameErr is why the certificate isn't valid for the host
+	// scanned, empty if it is
+	HostnameErr string
+}
+
+// inspectTLS completes a TLS handshake with port on addr, for host,
+// and returns what it showed, or nil if the port doesn't talk TLS
+func inspectTLS(ctx context.Context, host, addr string, port int, opts Options) *TLSInfo {
+	conn, err := dialContext(ctx, "tcp", net.JoinHostPort(addr, fmt.Sprint(port)), opts.Timeout)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	// the certificate is checked below, the handshake has to succeed
+	// whatever it is
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS10,
+	})
+
+	if err := tlsConn.SetDeadline(time.Now().Add(opts.Timeout)); err != nil {
+		return nil
+	}
+
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil
+	}
+
+	state := tlsConn.ConnectionState()
+	info := &TLSInfo{
+		Version: tls.VersionName(state.Version),
+		Cipher:  tls.CipherSuiteName(state.CipherSuite),
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		return info
+	}
+
+	cert := state.PeerCertificates[0]
+	info.Subject = cert.Subject.String()
+	info.Issuer = cert.Issuer.String()
+	info.SANs = append(info.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	info.NotAfter = cert.NotAfter
+	t := time.Now()
+	info.Expired = t.After(cert.NotAfter)
+	info.Expiring = !info.Expired && t.AddDate(0, 0, opts.ExpiryDays).After(cert.NotAfter)
+
+	if err := cert.VerifyHostname(host); err != nil {
+		info.HostnameErr = err.Error()
+	}
+
+	return info
+}
@@ -0,0 +1,149 @@
+package scan_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+)
+
+// certServer serves TLS with a self-signed certificate for names,
+// expiring at notAfter, and returns its port
+func certServer(t *testing.T, notAfter time.Time, names ...string) int {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0], Organization: []string{"pScan test"}},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	for _, n := range names {
+		if ip := net.ParseIP(n); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, n)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestTLSInspect(t *testing.T) {
+	inAYear := time.Now().AddDate(1, 0, 0).Truncate(time.Second)
+
+	testCases := []struct {
+		name           string
+		port           int
+		expectSANs     []string
+		expectExpired  bool
+		expectExpiring bool
+		expectMismatch bool
+	}{
+		{"Valid", certServer(t, inAYear, "web.test", "127.0.0.1"), []string{"web.test", "127.0.0.1"}, false, false, false},
+		{"Expiring", certServer(t, time.Now().AddDate(0, 0, 10), "127.0.0.1"), []string{"127.0.0.1"}, false, true, false},
+		{"Expired", certServer(t, time.Now().AddDate(0, 0, -1), "127.0.0.1"), []string{"127.0.0.1"}, true, false, false},
+		{"Mismatch", certServer(t, inAYear, "web.test"), []string{"web.test"}, false, false, true},
+	}
+
+	targets := []scan.Target{{Host: "127.0.0.1"}}
+	for _, tc := range testCases {
+		targets[0].Ports = append(targets[0].Ports, tc.port)
+	}
+
+	opts := scan.Options{TLSInspect: true, ExpiryDays: 30, Timeout: time.Second}
+	res := scan.RunTargets(targets, opts)
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := res[0].PortStates[i].TLS
+			if info == nil {
+				t.Fatalf("Expected TLS info for port %d\n", tc.port)
+			}
+
+			if info.Version != "TLS 1.3" || info.Cipher == "" {
+				t.Errorf("Expected TLS 1.3 and a cipher, got %q and %q instead\n", info.Version, info.Cipher)
+			}
+
+			subject := "CN=" + tc.expectSANs[0] + ",O=pScan test"
+			if info.Subject != subject || info.Issuer != subject {
+				t.Errorf("Expected subject and issuer %q, got %q and %q instead\n", subject, info.Subject, info.Issuer)
+			}
+
+			if !reflect.DeepEqual(info.SANs, tc.expectSANs) {
+				t.Errorf("Expected SANs %v, got %v instead\n", tc.expectSANs, info.SANs)
+			}
+
+			if tc.name == "Valid" && !info.NotAfter.Equal(inAYear) {
+				t.Errorf("Expected expiry %s, got %s instead\n", inAYear, info.NotAfter)
+			}
+
+			if info.Expired != tc.expectExpired || info.Expiring != tc.expectExpiring {
+				t.Errorf("Expected expired %t and expiring %t, got %t and %t instead\n",
+					tc.expectExpired, tc.expectExpiring, info.Expired, info.Expiring)
+			}
+
+			if (info.HostnameErr != "") != tc.expectMismatch {
+				t.Errorf("Expected hostname mismatch %t, got %q\n", tc.expectMismatch, info.HostnameErr)
+			}
+		})
+	}
+}
+
+func TestTLSInspectNotTLS(t *testing.T) {
+	port := bannerServer(t, "SSH-2.0-OpenSSH_9.6\r\n")
+	tlsPort := certServer(t, time.Now().AddDate(1, 0, 0), "127.0.0.1")
+
+	targets := []scan.Target{{Host: "127.0.0.1", Ports: []int{port, tlsPort}}}
+
+	res := scan.RunTargets(targets, scan.Options{TLSInspect: true, Timeout: time.Second})
+	if res[0].PortStates[0].TLS != nil {
+		t.Errorf("Expected no TLS info for a port without TLS, got %+v\n", res[0].PortStates[0].TLS)
+	}
+
+	res = scan.RunTargets(targets, scan.Options{Timeout: time.Second})
+	if res[0].PortStates[1].TLS != nil {
+		t.Errorf("Expected no TLS info without inspection, got %+v\n", res[0].PortStates[1].TLS)
+	}
+}
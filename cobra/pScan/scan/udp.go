@@ -0,0 +1,112 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+)
+
+// UDP services only answer requests they understand, these probes ask
+// for something every server of the protocol replies to
+var (
+	// DNS query for the NS records of the root zone
+	dnsProbe = []byte{
+		0x13, 0x37, // id
+		0x01, 0x00, // recursion desired
+		0x00, 0x01, // one question
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00,       // root
+		0x00, 0x02, // NS
+		0x00, 0x01, // IN
+	}
+
+	// NTPv3 client request
+	ntpProbe = append([]byte{0x1b}, make([]byte, 47)...)
+
+	// SNMPv1 get-request for sysDescr.0 with the public community
+	snmpProbe = []byte{
+		0x30, 0x26, 0x02, 0x01, 0x00,
+		0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa0, 0x19, 0x02, 0x01, 0x01, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+		0x30, 0x0e, 0x30, 0x0c,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00,
+		0x05, 0x00,
+	}
+)
+
+var udpProbes = map[int][]byte{
+	53:  dnsProbe,
+	123: ntpProbe,
+	161: snmpProbe,
+}
+
+// udpProbe returns the payload to send to port, an empty datagram for
+// ports without a known protocol
+func udpProbe(port int) []byte {
+	if p, ok := udpProbes[port]; ok {
+		return p
+	}
+
+	return []byte{}
+}
+
+// scanUDPPort sends a probe to a single UDP port. A reply means the
+// port is open and an ICMP port unreachable, reported by the system as
+// a refused connection, that it's closed. Without either, the port is
+// open or the probe was filtered
+func scanUDPPort(ctx context.Context, host string, port int, opts Options) PortState {
+	timeout := opts.Timeout
+	p := PortState{
+		Port:  port,
+		Proto: ProtoUDP,
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := dialContext(ctx, "udp", address, timeout)
+	if err != nil {
+		return udpFailure(p, err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	if _, err := conn.Write(udpProbe(port)); err != nil {
+		return udpFailure(p, err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return udpFailure(p, err)
+	}
+
+	// a done ctx stops the wait for a reply
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	buf := make([]byte, 512)
+	_, err = conn.Read(buf)
+	p.Latency = time.Since(start)
+	if err != nil {
+		return udpFailure(p, err)
+	}
+
+	p.State = StateOpen
+	if opts.ServiceDetect {
+		p.Service = udpServices[port]
+	}
+
+	return p
+}
+
+// udpFailure sets the state of p from err, a silent port is
+// open|filtered rather than filtered as with TCP
+func udpFailure(p PortState, err error) PortState {
+	p.State = classify(err)
+	switch p.State {
+	case StateFiltered:
+		p.State = StateOpenFiltered
+	case StateError:
+		p.Err = err
+	}
+
+	return p
+}
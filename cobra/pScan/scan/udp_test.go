@@ -0,0 +1,131 @@
+package scan_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bedminer1/cobra/pScan/scan"
+)
+
+// udpListener starts a UDP server on localhost that answers probes
+// if reply is set, and returns its port
+func udpListener(t *testing.T, reply bool) (int, <-chan []byte) {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			select {
+			case received <- append([]byte{}, buf[:n]...):
+			default:
+			}
+
+			if reply {
+				conn.WriteTo([]byte("pong"), addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().(*net.UDPAddr).Port, received
+}
+
+func TestRunUDP(t *testing.T) {
+	openPort, received := udpListener(t, true)
+	silentPort, _ := udpListener(t, false)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+
+	testCases := []struct {
+		name        string
+		port        int
+		expectState string
+	}{
+		{"OpenPort", openPort, "open"},
+		{"SilentPort", silentPort, "open|filtered"},
+		{"ClosedPort", closedPort, "closed"},
+	}
+
+	hl := &scan.HostsList{}
+	hl.Add("127.0.0.1")
+
+	ports := []int{}
+	for _, tc := range testCases {
+		ports = append(ports, tc.port)
+	}
+
+	res := scan.Run(hl, ports, scan.Options{UDP: true, Timeout: 200 * time.Millisecond})
+	if len(res) != 1 {
+		t.Fatalf("expected 1 result, got %d instead\n", len(res))
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := res[0].PortStates[i]
+			if ps.Port != tc.port {
+				t.Errorf("expected port %d, got %d\n", tc.port, ps.Port)
+			}
+
+			if ps.Proto != scan.ProtoUDP {
+				t.Errorf("expected protocol %q, got %q\n", scan.ProtoUDP, ps.Proto)
+			}
+
+			if ps.State.String() != tc.expectState {
+				t.Errorf("Expected port %d to be %s, got %s\n", tc.port, tc.expectState, ps.State)
+			}
+		})
+	}
+
+	select {
+	case probe := <-received:
+		if len(probe) != 0 {
+			t.Errorf("expected an empty probe for an unknown port, got %q\n", probe)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the listener to receive a probe")
+	}
+}
+
+func TestUDPProbe(t *testing.T) {
+	testCases := []struct {
+		name        string
+		port        int
+		expectLen   int
+		expectFirst byte
+	}{
+		{"DNS", 53, 17, 0x13},
+		{"NTP", 123, 48, 0x1b},
+		{"SNMP", 161, 40, 0x30},
+		{"Unknown", 9999, 0, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			probe := scan.UDPProbe(tc.port)
+			if len(probe) != tc.expectLen {
+				t.Fatalf("expected probe of %d bytes, got %d\n", tc.expectLen, len(probe))
+			}
+
+			if tc.expectLen > 0 && probe[0] != tc.expectFirst {
+				t.Errorf("expected probe to start with %#x, got %#x\n", tc.expectFirst, probe[0])
+			}
+		})
+	}
+}
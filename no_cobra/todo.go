@@ -14,6 +14,7 @@ type item struct {
 	Done bool
 	CreatedAt time.Time
 	CompletedAt time.Time
+	Due time.Time
 }
 
 // List represents a list of TODO items
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bedminer1/personal/todo/todo"
+)
+
+func setup(t *testing.T, tasks []string, initList bool) (string, func()) {
+	tf, err := os.CreateTemp("", "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf.Close()
+
+	if initList {
+		tl := &todo.TaskList{}
+		for _, task := range tasks {
+			if err := tl.Add(task); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := tl.Save(tf.Name()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return tf.Name(), func() { os.Remove(tf.Name()) }
+}
+
+func TestTaskActions(t *testing.T) {
+	tasks := []string{"task1", "task2", "task3"}
+
+	testCases := []struct {
+		name           string
+		args           []string
+		expectedOut    string
+		expectedList   string
+		initList       bool
+		actionFunction func(io.Writer, string, []string) error
+	}{
+		{
+			name:           "AddAction",
+			args:           tasks,
+			expectedOut:    "Added Task: task1\nAdded Task: task2\nAdded Task: task3\n",
+			expectedList:   "1: task1 - Not Done\n2: task2 - Not Done\n3: task3 - Not Done\n",
+			actionFunction: addTask,
+		},
+		{
+			name:           "ListAction",
+			expectedOut:    "1: task1 - Not Done\n2: task2 - Not Done\n3: task3 - Not Done\n",
+			expectedList:   "1: task1 - Not Done\n2: task2 - Not Done\n3: task3 - Not Done\n",
+			initList:       true,
+			actionFunction: func(out io.Writer, tasksFile string, args []string) error { return listAction(out, tasksFile) },
+		},
+		{
+			name:           "DeleteAction",
+			args:           []string{"1", "3"},
+			expectedOut:    "Deleted task 1: task1\nDeleted task 3: task3\n",
+			expectedList:   "1: task2 - Not Done\n",
+			initList:       true,
+			actionFunction: deleteAction,
+		},
+		{
+			name:           "CompleteAction",
+			args:           []string{"2", "3", "2"},
+			expectedOut:    "Completed task 2: task2\nCompleted task 3: task3\n",
+			expectedList:   "1: task1 - Not Done\n2: task2 - Done\n3: task3 - Done\n",
+			initList:       true,
+			actionFunction: completeAction,
+		},
+		{
+			name:           "EditAction",
+			args:           []string{"2", "buy milk"},
+			expectedOut:    "Edited task 2: task2 -> buy milk\n",
+			expectedList:   "1: task1 - Not Done\n2: buy milk - Not Done\n3: task3 - Not Done\n",
+			initList:       true,
+			actionFunction: func(out io.Writer, tasksFile string, args []string) error { return editAction(out, tasksFile, args[0], args[1]) },
+		},
+		{
+			name:           "ClearDoneNone",
+			expectedOut:    "Cleared 0 done tasks\n",
+			expectedList:   "1: task1 - Not Done\n2: task2 - Not Done\n3: task3 - Not Done\n",
+			initList:       true,
+			actionFunction: func(out io.Writer, tasksFile string, args []string) error { return clearDoneAction(out, tasksFile) },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tf, cleanup := setup(t, tasks, tc.initList)
+			defer cleanup()
+
+			var out bytes.Buffer
+			if err := tc.actionFunction(&out, tf, tc.args); err != nil {
+				t.Fatalf("Expected no error, got %q\n", err)
+			}
+
+			if out.String() != tc.expectedOut {
+				t.Errorf("Expected output %q, got %q\n", tc.expectedOut, out.String())
+			}
+
+			var list bytes.Buffer
+			if err := listAction(&list, tf); err != nil {
+				t.Fatalf("Expected no error, got %q\n", err)
+			}
+
+			if list.String() != tc.expectedList {
+				t.Errorf("Expected list %q, got %q\n", tc.expectedList, list.String())
+			}
+		})
+	}
+}
+
+func TestTaskActionErrors(t *testing.T) {
+	tasks := []string{"task1", "task2"}
+
+	testCases := []struct {
+		name      string
+		action    func(io.Writer, string) error
+		expectErr error
+		expectMsg string
+	}{
+		{
+			name:      "AddExisting",
+			action:    func(out io.Writer, tf string) error { return addTask(out, tf, []string{"task1"}) },
+			expectErr: todo.ErrExists,
+		},
+		{
+			name:      "DeleteNotExists",
+			action:    func(out io.Writer, tf string) error { return deleteAction(out, tf, []string{"1", "3"}) },
+			expectErr: todo.ErrNotExists,
+		},
+		{
+			name:      "DeleteInvalidID",
+			action:    func(out io.Writer, tf string) error { return deleteAction(out, tf, []string{"one"}) },
+			expectErr: strconv.ErrSyntax,
+			expectMsg: `invalid task ID "one"`,
+		},
+		{
+			name:      "CompleteNotExists",
+			action:    func(out io.Writer, tf string) error { return completeAction(out, tf, []string{"0"}) },
+			expectErr: todo.ErrNotExists,
+		},
+		{
+			name:      "EditNotExists",
+			action:    func(out io.Writer, tf string) error { return editAction(out, tf, "5", "task5") },
+			expectErr: todo.ErrNotExists,
+		},
+		{
+			name:      "EditExisting",
+			action:    func(out io.Writer, tf string) error { return editAction(out, tf, "1", "task2") },
+			expectErr: todo.ErrExists,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tf, cleanup := setup(t, tasks, true)
+			defer cleanup()
+
+			var out bytes.Buffer
+			err := tc.action(&out, tf)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("Expected error %q, got %v instead\n", tc.expectErr, err)
+			}
+
+			if tc.expectMsg != "" && !strings.Contains(err.Error(), tc.expectMsg) {
+				t.Errorf("Expected error containing %q, got %q\n", tc.expectMsg, err)
+			}
+
+			// a failed action leaves the list as it was
+			var list bytes.Buffer
+			if err := listAction(&list, tf); err != nil {
+				t.Fatalf("Expected no error, got %q\n", err)
+			}
+
+			expectedList := "1: task1 - Not Done\n2: task2 - Not Done\n"
+			if list.String() != expectedList {
+				t.Errorf("Expected list %q, got %q\n", expectedList, list.String())
+			}
+		})
+	}
+}
+
+func TestIntegration(t *testing.T) {
+	tasks := []string{"task1", "task2", "task3", "task4"}
+
+	tf, cleanup := setup(t, tasks, false)
+	defer cleanup()
+
+	var out bytes.Buffer
+
+	// add, complete, edit, clear the done tasks, delete and list
+	if err := addTask(&out, tf, tasks); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	if err := completeAction(&out, tf, []string{"1", "3"}); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	if err := editAction(&out, tf, "4", "task four"); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	if err := clearDoneAction(&out, tf); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	if err := deleteAction(&out, tf, []string{"1"}); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	if err := listAction(&out, tf); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	expectedOut := "Added Task: task1\nAdded Task: task2\nAdded Task: task3\nAdded Task: task4\n" +
+		"Completed task 1: task1\nCompleted task 3: task3\n" +
+		"Edited task 4: task4 -> task four\n" +
+		"Cleared 2 done tasks\n" +
+		"Deleted task 1: task2\n" +
+		"1: task four - Not Done\n"
+
+	if out.String() != expectedOut {
+		t.Errorf("Expected output %q, got %q\n", expectedOut, out.String())
+	}
+}
@@ -16,8 +16,12 @@ import (
 var addCmd = &cobra.Command{
 	Use:     "add <task1>...<taskn>",
 	Aliases: []string{"a"},
-	Short:   "Adding a todo task to the list of todos",
-	Args:    cobra.MinimumNArgs(1),
+	Short:   "Add tasks to the list",
+	Long: `Add a task for each argument, quote names with spaces. Tasks are
+added at the end of the list, not done, and no two tasks can have the
+same name.`,
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
 
 	RunE: func(cmd *cobra.Command, args []string) error {
 		tasksFile, err := cmd.Flags().GetString("tasks-file")
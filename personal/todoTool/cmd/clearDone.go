@@ -0,0 +1,52 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bedminer1/personal/todo/todo"
+	"github.com/spf13/cobra"
+)
+
+// clearDoneCmd represents the clear-done command
+var clearDoneCmd = &cobra.Command{
+	Use:     "clear-done",
+	Aliases: []string{"cd", "clear"},
+	Short:   "Delete the tasks that are done",
+	Long: `Delete every task marked as done by complete. The tasks left are
+numbered again from 1.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tasksFile, err := cmd.Flags().GetString("tasks-file")
+		if err != nil {
+			return err
+		}
+
+		return clearDoneAction(os.Stdout, tasksFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(clearDoneCmd)
+}
+
+func clearDoneAction(out io.Writer, tasksFile string) error {
+	tl := &todo.TaskList{}
+	if err := tl.Load(tasksFile); err != nil {
+		return err
+	}
+
+	cleared := tl.ClearDone()
+	fmt.Fprintf(out, "Cleared %d done tasks\n", cleared)
+	if cleared == 0 {
+		return nil
+	}
+
+	return tl.Save(tasksFile)
+}
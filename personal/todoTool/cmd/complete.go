@@ -0,0 +1,67 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bedminer1/personal/todo/todo"
+	"github.com/spf13/cobra"
+)
+
+// completeCmd represents the complete command
+var completeCmd = &cobra.Command{
+	Use:     "complete <id1>...<idn>",
+	Aliases: []string{"c", "done"},
+	Short:   "Mark tasks as done",
+	Long: `Mark the tasks with the IDs given, as shown by list, as done.
+
+Every ID is checked before any task is changed. Tasks done already keep
+the time they were completed.`,
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tasksFile, err := cmd.Flags().GetString("tasks-file")
+		if err != nil {
+			return err
+		}
+
+		return completeAction(os.Stdout, tasksFile, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(completeCmd)
+}
+
+func completeAction(out io.Writer, tasksFile string, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	tl := &todo.TaskList{}
+	if err := tl.Load(tasksFile); err != nil {
+		return err
+	}
+
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		if names[i], err = tl.Get(id); err != nil {
+			return err
+		}
+	}
+
+	for i, id := range ids {
+		if err := tl.Complete(id); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Completed task %d: %s\n", id, names[i])
+	}
+
+	return tl.Save(tasksFile)
+}
@@ -1,40 +1,95 @@
 /*
-Copyright © 2024 NAME HERE <EMAIL ADDRESS>
-
+Copyright © 2024 bedminer1
 */
 package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
 
+	"github.com/bedminer1/personal/todo/todo"
 	"github.com/spf13/cobra"
 )
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("delete called")
+	Use:     "delete <id1>...<idn>",
+	Aliases: []string{"d", "del", "rm"},
+	Short:   "Delete tasks from the list",
+	Long: `Delete the tasks with the IDs given, as shown by list.
+
+Every ID is checked before any task is deleted, so a wrong ID leaves
+the list as it was. Use clear-done to delete all the tasks done.`,
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tasksFile, err := cmd.Flags().GetString("tasks-file")
+		if err != nil {
+			return err
+		}
+
+		return deleteAction(os.Stdout, tasksFile, args)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(deleteCmd)
+}
+
+func deleteAction(out io.Writer, tasksFile string, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	tl := &todo.TaskList{}
+	if err := tl.Load(tasksFile); err != nil {
+		return err
+	}
+
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		if names[i], err = tl.Get(id); err != nil {
+			return err
+		}
+	}
+
+	// removing a task moves the ones after it up, so remove the last
+	// ones first
+	sorted := append([]int{}, ids...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	for _, id := range sorted {
+		if err := tl.Remove(id); err != nil {
+			return err
+		}
+	}
+
+	for i, id := range ids {
+		fmt.Fprintf(out, "Deleted task %d: %s\n", id, names[i])
+	}
+
+	return tl.Save(tasksFile)
+}
 
-	// Here you will define your flags and configuration settings.
+// parseIDs returns the task IDs in args, once each
+func parseIDs(args []string) ([]int, error) {
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, a := range args {
+		id, err := strconv.Atoi(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid task ID %q: %w", a, err)
+		}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// deleteCmd.PersistentFlags().String("foo", "", "A help for foo")
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// deleteCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	return ids, nil
 }
@@ -0,0 +1,63 @@
+/*
+Copyright © 2024 bedminer1
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bedminer1/personal/todo/todo"
+	"github.com/spf13/cobra"
+)
+
+// editCmd represents the edit command
+var editCmd = &cobra.Command{
+	Use:     "edit <id> <name>",
+	Aliases: []string{"e", "rename"},
+	Short:   "Rename a task",
+	Long: `Rename the task with the ID given, as shown by list. Quote names
+with spaces. The task keeps its place in the list and whether it is
+done, and no two tasks can have the same name.`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(2),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tasksFile, err := cmd.Flags().GetString("tasks-file")
+		if err != nil {
+			return err
+		}
+
+		return editAction(os.Stdout, tasksFile, args[0], args[1])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+}
+
+func editAction(out io.Writer, tasksFile, idArg, name string) error {
+	ids, err := parseIDs([]string{idArg})
+	if err != nil {
+		return err
+	}
+	id := ids[0]
+
+	tl := &todo.TaskList{}
+	if err := tl.Load(tasksFile); err != nil {
+		return err
+	}
+
+	old, err := tl.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if err := tl.Edit(id, name); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Edited task %d: %s -> %s\n", id, old, name)
+
+	return tl.Save(tasksFile)
+}
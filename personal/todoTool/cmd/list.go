@@ -13,18 +13,18 @@ import (
 
 // listCmd represents the list command
 var listCmd = &cobra.Command{
-	Use:   "list",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+	Use:     "list",
+	Aliases: []string{"l", "ls"},
+	Short:   "List the tasks",
+	Long: `List the tasks in the order they were added, each with its ID and
+whether it is done. The IDs are what delete, complete and edit take.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+
 	RunE: func(cmd *cobra.Command, args []string) error {
 		tasksFile, err := cmd.Flags().GetString("tasks-file")
 		if err != nil {
-			return nil
+			return err
 		}
 
 		return listAction(io.Writer(os.Stdout), tasksFile)
@@ -16,6 +16,12 @@ import (
 var rootCmd = &cobra.Command{
 	Use:   "todo",
 	Short: "A CLI todo tool",
+	Long: `Keep a list of tasks to do.
+
+Add tasks with add, see them with list, mark them as done with complete
+and rename them with edit. Tasks are deleted by ID with delete, or all
+at once when done with clear-done. The list is kept in the tasks file,
+tasks.txt unless --tasks-file is given.`,
 
 	// Run: func(cmd *cobra.Command, args []string) { },
 }
@@ -10,6 +10,11 @@ import (
 	"time"
 )
 
+var (
+	ErrExists    = errors.New("task already exists")
+	ErrNotExists = errors.New("task does not exist")
+)
+
 type task struct {
 	Name        string
 	Done        bool
@@ -37,7 +42,7 @@ func (tl *TaskList) search(task string) (bool, int) {
 
 func (tl *TaskList) Add(name string) error{
 	if found, _ := tl.search(name); found {
-		return fmt.Errorf("task Already Exists: %s", name)
+		return fmt.Errorf("%w: %s", ErrExists, name)
 	}
 
 	newTask := task{
@@ -53,12 +58,67 @@ func (tl *TaskList) Add(name string) error{
 func (tl *TaskList) Remove(id int) error {
 	id--
 	if id < 0 || id >= len(tl.Tasks) {
-		return fmt.Errorf("item %d does not exist", id+1)
+		return fmt.Errorf("%w: %d", ErrNotExists, id+1)
 	}
 	tl.Tasks = append(tl.Tasks[:id], tl.Tasks[id+1:]...)
 	return nil
 }
 
+// Get returns the name of task id, counting from 1 as List does
+func (tl *TaskList) Get(id int) (string, error) {
+	if id < 1 || id > len(tl.Tasks) {
+		return "", fmt.Errorf("%w: %d", ErrNotExists, id)
+	}
+
+	return tl.Tasks[id-1].Name, nil
+}
+
+// Complete marks task id as done. Tasks done already keep the time
+// they were completed
+func (tl *TaskList) Complete(id int) error {
+	if id < 1 || id > len(tl.Tasks) {
+		return fmt.Errorf("%w: %d", ErrNotExists, id)
+	}
+
+	t := &tl.Tasks[id-1]
+	if !t.Done {
+		t.Done = true
+		t.CompletedAt = time.Now()
+	}
+
+	return nil
+}
+
+// Edit renames task id to name, unless another task has that name
+func (tl *TaskList) Edit(id int, name string) error {
+	if id < 1 || id > len(tl.Tasks) {
+		return fmt.Errorf("%w: %d", ErrNotExists, id)
+	}
+
+	for i, t := range tl.Tasks {
+		if t.Name == name && i != id-1 {
+			return fmt.Errorf("%w: %s", ErrExists, name)
+		}
+	}
+
+	tl.Tasks[id-1].Name = name
+	return nil
+}
+
+// ClearDone removes the tasks that are done and returns how many
+func (tl *TaskList) ClearDone() int {
+	left := []task{}
+	for _, t := range tl.Tasks {
+		if !t.Done {
+			left = append(left, t)
+		}
+	}
+
+	cleared := len(tl.Tasks) - len(left)
+	tl.Tasks = left
+	return cleared
+}
+
 func (tl *TaskList) List(out io.Writer) error {
 	for i, t := range tl.Tasks {
 		fmt.Fprintf(out, "%d: %s", i+1, t.Name)
@@ -0,0 +1,83 @@
+package todo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bedminer1/personal/todo/todo"
+)
+
+func TestComplete(t *testing.T) {
+	tl := &todo.TaskList{}
+	tl.Add("task1")
+
+	if err := tl.Complete(1); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	done := tl.Tasks[0]
+	if !done.Done || done.CompletedAt.IsZero() {
+		t.Fatalf("Expected task1 done with a completion time, got %+v\n", done)
+	}
+
+	// completing it again keeps the time it was done
+	if err := tl.Complete(1); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	if !tl.Tasks[0].CompletedAt.Equal(done.CompletedAt) {
+		t.Errorf("Expected completion time %s, got %s\n", done.CompletedAt, tl.Tasks[0].CompletedAt)
+	}
+
+	if err := tl.Complete(2); !errors.Is(err, todo.ErrNotExists) {
+		t.Errorf("Expected error %q, got %v\n", todo.ErrNotExists, err)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	tl := &todo.TaskList{}
+	tl.Add("task1")
+	tl.Add("task2")
+
+	testCases := []struct {
+		name      string
+		id        int
+		newName   string
+		expectErr error
+	}{
+		{"Rename", 1, "task one", nil},
+		{"SameName", 2, "task2", nil},
+		{"Exists", 2, "task one", todo.ErrExists},
+		{"NotExists", 3, "task3", todo.ErrNotExists},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tl.Edit(tc.id, tc.newName)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("Expected error %v, got %v\n", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && tl.Tasks[tc.id-1].Name != tc.newName {
+				t.Errorf("Expected task %d named %q, got %q\n", tc.id, tc.newName, tl.Tasks[tc.id-1].Name)
+			}
+		})
+	}
+}
+
+func TestClearDone(t *testing.T) {
+	tl := &todo.TaskList{}
+	for _, name := range []string{"task1", "task2", "task3"} {
+		tl.Add(name)
+	}
+	tl.Complete(1)
+	tl.Complete(3)
+
+	if n := tl.ClearDone(); n != 2 {
+		t.Errorf("Expected 2 tasks cleared, got %d\n", n)
+	}
+
+	if len(tl.Tasks) != 1 || tl.Tasks[0].Name != "task2" {
+		t.Errorf("Expected only task2 left, got %+v\n", tl.Tasks)
+	}
+}